@@ -2,7 +2,11 @@ package main
 
 import (
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/Nhanderu/ipe/ipefmt"
 	"golang.org/x/crypto/ssh/terminal"
@@ -24,6 +28,15 @@ func main() {
 
 func parseArgs() (ipefmt.ArgsInfo, error) {
 	var args ipefmt.ArgsInfo
+	var align string
+	var columns string
+	var dircolors string
+	var iconsFile string
+	var printf string
+	var theme string
+	var tmpl string
+	var utc bool
+	var tz string
 
 	kingpin.Arg("sources", "defines the directories to list contents").
 		Default(".").
@@ -33,7 +46,11 @@ func parseArgs() (ipefmt.ArgsInfo, error) {
 		Short('x').
 		BoolVar(&args.Across)
 
-	kingpin.Flag("all", "shows all entries").
+	kingpin.Flag("align", "defines the alignment of long view columns, like \"size=left,name=right\"").
+		PlaceHolder("COLUMN=ALIGNMENT").
+		StringVar(&align)
+
+	kingpin.Flag("all", "shows all entries, including the dotfiles; the entries ignored by Git or by ignore files are still hidden, see --show-ignored and --no-ignore-files").
 		Short('a').
 		BoolVar(&args.All)
 
@@ -48,10 +65,26 @@ func parseArgs() (ipefmt.ArgsInfo, error) {
 			ipefmt.ArgColorAlways,
 			ipefmt.ArgColorAuto)
 
+	kingpin.Flag("columns", "defines the columns of long view and their order, separated by commas").
+		PlaceHolder("COLUMNS").
+		StringVar(&columns)
+
 	kingpin.Flag("classify", "appends indicator to the entries").
 		Short('F').
 		BoolVar(&args.Classify)
 
+	kingpin.Flag("control-chars", "controls whether non-printable characters in names are shown as \"?\"").
+		Default(ipefmt.ArgControlAuto).
+		PlaceHolder("WHEN").
+		EnumVar(&args.ControlChars,
+			ipefmt.ArgControlHide,
+			ipefmt.ArgControlShow,
+			ipefmt.ArgControlAuto)
+
+	kingpin.Flag("dircolors", "defines the file with the colors of the names, in `dircolors` format, instead of LS_COLORS").
+		PlaceHolder("FILE").
+		ExistingFileVar(&dircolors)
+
 	kingpin.Flag("depth", "defines maximum depth of recursion").
 		Short('D').
 		PlaceHolder("LEVELS").
@@ -68,6 +101,36 @@ func parseArgs() (ipefmt.ArgsInfo, error) {
 	kingpin.Flag("follow", "dereferences symbolic links").
 		BoolVar(&args.Follow)
 
+	kingpin.Flag("format", "defines the output format").
+		Default(ipefmt.ArgFormatText).
+		PlaceHolder("FORMAT").
+		EnumVar(&args.Format, ipefmt.Formats()...)
+
+	kingpin.Flag("git", "shows the Git status of the entries, in long and tree views").
+		BoolVar(&args.Git)
+
+	kingpin.Flag("git-commit", "shows the date, hash and author of the last commit of the entries in long view").
+		BoolVar(&args.GitCommit)
+
+	kingpin.Flag("git-header", "shows the branch of the repository of each source, and if it's dirty, ahead or behind").
+		BoolVar(&args.GitHeader)
+
+	kingpin.Flag("git-ignore", "hides the entries ignored by Git; use --no-git-ignore to show them").
+		Default("true").
+		BoolVar(&args.GitIgnore)
+
+	kingpin.Flag("glob", "shows only the entries that matches any of the globs, with \"**\" for any directories; directories are always shown when recursing").
+		PlaceHolder("GLOB").
+		StringsVar(&args.Glob)
+
+	kingpin.Flag("glob-case-insensitive", "matches globs ignoring case").
+		BoolVar(&args.GlobFoldCase)
+
+	kingpin.Flag("glob-match", "defines if globs match the name or the path relative to the source").
+		Default(ipefmt.ArgGlobName).
+		PlaceHolder("WHAT").
+		EnumVar(&args.GlobMatch, ipefmt.ArgGlobName, ipefmt.ArgGlobPath)
+
 	kingpin.Flag("group", "shows group alongside user").
 		Short('g').
 		BoolVar(&args.Group)
@@ -76,11 +139,29 @@ func parseArgs() (ipefmt.ArgsInfo, error) {
 		Short('H').
 		BoolVar(&args.Header)
 
+	kingpin.Flag("human", "shows human-readable values in machine-readable formats").
+		BoolVar(&args.Human)
+
+	kingpin.Flag("icons", "shows icons before the names, which need a Nerd Font").
+		BoolVar(&args.Icons)
+
+	kingpin.Flag("icons-file", "defines the file with the icons, instead of the \"ipe/icons\" file in the config directory").
+		PlaceHolder("FILE").
+		ExistingFileVar(&iconsFile)
+
 	kingpin.Flag("ignore", "hides every entry that matches the pattern").
 		Short('I').
 		PlaceHolder("PATTERN").
 		RegexpListVar(&args.Ignore)
 
+	kingpin.Flag("ignore-files", "hides the entries matching the .ipeignore and .ignore files; use --no-ignore-files to show them").
+		Default("true").
+		BoolVar(&args.IgnoreFiles)
+
+	kingpin.Flag("ignore-glob", "hides every entry that matches the glob").
+		PlaceHolder("GLOB").
+		StringsVar(&args.IgnoreGlob)
+
 	kingpin.Flag("inode", "shows entry inode in long view").
 		Short('i').
 		BoolVar(&args.Inode)
@@ -92,6 +173,20 @@ func parseArgs() (ipefmt.ArgsInfo, error) {
 		Short('l').
 		BoolVar(&args.Long)
 
+	kingpin.Flag("markdown-links", "links the entries to their files in Markdown format").
+		BoolVar(&args.MarkdownLinks)
+
+	kingpin.Flag("markdown-style", "defines how the tree is written in Markdown format").
+		Default(ipefmt.ArgMarkdownFence).
+		PlaceHolder("STYLE").
+		EnumVar(&args.MarkdownStyle,
+			ipefmt.ArgMarkdownFence,
+			ipefmt.ArgMarkdownList)
+
+	kingpin.Flag("null", "ends each entry with a NUL character instead of a newline").
+		Short('0').
+		BoolVar(&args.Null)
+
 	kingpin.Flag("one-line", "shows one entry per line").
 		Short('1').
 		BoolVar(&args.OneLine)
@@ -100,6 +195,20 @@ func parseArgs() (ipefmt.ArgsInfo, error) {
 		Short('r').
 		BoolVar(&args.Reverse)
 
+	kingpin.Flag("printf", "formats each entry with a Go template, interpreting escapes like \\n and \\t").
+		PlaceHolder("TEMPLATE").
+		StringVar(&printf)
+
+	kingpin.Flag("quoting-style", "defines how names are quoted").
+		Default(ipefmt.ArgQuotingLiteral).
+		PlaceHolder("STYLE").
+		EnumVar(&args.Quoting,
+			ipefmt.ArgQuotingLiteral,
+			ipefmt.ArgQuotingShell,
+			ipefmt.ArgQuotingShellEscape,
+			ipefmt.ArgQuotingC,
+			ipefmt.ArgQuotingEscape)
+
 	kingpin.Flag("recursive", "lists subdirectories recursively").
 		Short('R').
 		BoolVar(&args.Recursive)
@@ -108,16 +217,7 @@ func parseArgs() (ipefmt.ArgsInfo, error) {
 		Short('s').
 		Default(ipefmt.ArgSortNone).
 		PlaceHolder("COLUMN").
-		EnumVar(&args.Sort,
-			ipefmt.ArgSortNone,
-			ipefmt.ArgSortInode,
-			ipefmt.ArgSortMode,
-			ipefmt.ArgSortSize,
-			ipefmt.ArgSortAccessed,
-			ipefmt.ArgSortModified,
-			ipefmt.ArgSortCreated,
-			ipefmt.ArgSortUser,
-			ipefmt.ArgSortName)
+		EnumVar(&args.Sort, append([]string{ipefmt.ArgSortNone}, ipefmt.Columns()...)...)
 
 	kingpin.Flag("separator", "defines the separator of the columns").
 		Short('S').
@@ -125,6 +225,36 @@ func parseArgs() (ipefmt.ArgsInfo, error) {
 		PlaceHolder("STRING").
 		StringVar(&args.Separator)
 
+	kingpin.Flag("show-ignored", "shows the entries ignored by Git dimmed, instead of hiding them").
+		BoolVar(&args.ShowIgnored)
+
+	kingpin.Flag("size-decimals", "defines the number of decimal places of human-readable sizes").
+		Default("1").
+		PlaceHolder("DIGITS").
+		Uint8Var(&args.SizeDecimals)
+
+	kingpin.Flag("size-style", "defines how sizes are shown in long view").
+		Default(ipefmt.ArgSizeIEC).
+		PlaceHolder("STYLE").
+		EnumVar(&args.SizeStyle,
+			ipefmt.ArgSizeIEC,
+			ipefmt.ArgSizeSI,
+			ipefmt.ArgSizeBytes,
+			ipefmt.ArgSizeBlocks)
+
+	kingpin.Flag("theme", "defines the colors of the long view columns: "+strings.Join(ipefmt.Themes(), ", ")+" or a theme file").
+		Default(ipefmt.ArgThemeDefault).
+		PlaceHolder("THEME").
+		StringVar(&theme)
+
+	kingpin.Flag("thousands-separator", "defines the separator of thousands in exact sizes").
+		PlaceHolder("STRING").
+		StringVar(&args.Thousands)
+
+	kingpin.Flag("template", "formats each entry with a Go template, in its own line").
+		PlaceHolder("TEMPLATE").
+		StringVar(&tmpl)
+
 	kingpin.Flag("time", "defines which timestamps to show").
 		Short('T').
 		Default(ipefmt.ArgTimeMod).
@@ -134,14 +264,107 @@ func parseArgs() (ipefmt.ArgsInfo, error) {
 			ipefmt.ArgTimeMod,
 			ipefmt.ArgTimeCrt)
 
+	kingpin.Flag("time-style", "defines how timestamps are shown: default, iso, long-iso, full-iso, relative, unix or a Go layout after a \"+\", like +2006-01-02").
+		Default(ipefmt.ArgTimeStyleDefault).
+		PlaceHolder("STYLE").
+		StringVar(&args.TimeStyle)
+
 	kingpin.Flag("tree", "display entries in \"tree view\"").
 		Short('t').
 		BoolVar(&args.Tree)
 
+	kingpin.Flag("tz", "defines the time zone timestamps are shown in").
+		PlaceHolder("ZONE").
+		StringVar(&tz)
+
+	kingpin.Flag("utc", "shows timestamps in UTC").
+		BoolVar(&utc)
+
 	kingpin.CommandLine.HelpFlag.Short('h')
 
 	kingpin.Parse()
-	var err error
-	args.Width, _, err = terminal.GetSize(int(os.Stdout.Fd()))
-	return args, err
+	if columns != "" {
+		var err error
+		args.Columns, err = ipefmt.ParseColumns(columns)
+		kingpin.FatalIfError(err, "invalid columns")
+	}
+	if tmpl != "" && printf != "" {
+		kingpin.Fatalf("--template and --printf can't be used together")
+	}
+	if tmpl != "" {
+		printf = tmpl + "\n"
+	} else if printf != "" {
+		printf = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\t`, "\t", `\0`, "\x00").Replace(printf)
+	}
+	if printf != "" {
+		if args.Format != ipefmt.ArgFormatText {
+			kingpin.Fatalf("--template and --printf can't be used with --format %s", args.Format)
+		}
+		var err error
+		args.Template, err = ipefmt.ParseTemplate(printf)
+		kingpin.FatalIfError(err, "invalid template")
+	}
+	if dircolors != "" {
+		file, err := os.Open(dircolors)
+		kingpin.FatalIfError(err, "invalid dircolors file")
+		args.NameColors, err = ipefmt.ParseDircolors(file)
+		file.Close()
+		kingpin.FatalIfError(err, "invalid dircolors file")
+	} else if lsColors := os.Getenv("LS_COLORS"); lsColors != "" {
+		args.NameColors = ipefmt.ParseLSColors(lsColors)
+	}
+	if iconsFile == "" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			if _, err := os.Stat(filepath.Join(dir, "ipe", "icons")); err == nil {
+				iconsFile = filepath.Join(dir, "ipe", "icons")
+			}
+		}
+	}
+	if args.Icons && iconsFile != "" {
+		file, err := os.Open(iconsFile)
+		kingpin.FatalIfError(err, "invalid icons file")
+		args.IconSet, err = ipefmt.ParseIconSet(file)
+		file.Close()
+		kingpin.FatalIfError(err, "invalid icons file")
+	}
+	if builtin, ok := ipefmt.BuiltinTheme(theme); ok {
+		args.Theme = builtin
+	} else {
+		file, err := os.Open(theme)
+		kingpin.FatalIfError(err, "invalid theme")
+		args.Theme, err = ipefmt.ParseTheme(file)
+		file.Close()
+		kingpin.FatalIfError(err, "invalid theme file")
+	}
+	if align != "" {
+		var err error
+		args.Align, err = ipefmt.ParseAlign(align)
+		kingpin.FatalIfError(err, "invalid alignment")
+	}
+	if args.TimeStyle != "" {
+		var err error
+		args.TimeStyle, err = ipefmt.ParseTimeStyle(args.TimeStyle)
+		kingpin.FatalIfError(err, "invalid time style")
+	}
+	if utc {
+		args.Location = time.UTC
+	} else if tz != "" {
+		loc, err := time.LoadLocation(tz)
+		kingpin.FatalIfError(err, "invalid time zone")
+		args.Location = loc
+	}
+	args.Width = terminalWidth()
+	return args, nil
+}
+
+// terminalWidth returns the width of the terminal, or the one in the
+// `COLUMNS` environment variable when the output isn't a terminal.
+func terminalWidth() int {
+	if width, _, err := terminal.GetSize(int(os.Stdout.Fd())); err == nil {
+		return width
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return 80
 }
@@ -147,6 +147,9 @@ func (f File) FollowLink() (File, error) {
 	if err != nil {
 		return File{}, err
 	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(f.dir, path)
+	}
 	return Read(path)
 }
 
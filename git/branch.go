@@ -0,0 +1,171 @@
+package git
+
+import (
+	"container/heap"
+	"strings"
+)
+
+// Branch represents the current branch of a repository and how it compares
+// to its upstream branch, as far as the local references know.
+type Branch struct {
+	// Name is the short name of the branch, like "master". It's empty when
+	// HEAD is detached.
+	Name string
+	// Head is the commit HEAD points to. It's zero if the branch has no
+	// commits.
+	Head Hash
+	// Upstream is the short name of the upstream branch, like
+	// "origin/master". It's empty if there's none or it was never fetched.
+	Upstream string
+	// Ahead and Behind are the number of commits only in the branch and
+	// only in the upstream branch.
+	Ahead  int
+	Behind int
+}
+
+// Detached reports whether HEAD points to a commit instead of a branch.
+func (b Branch) Detached() bool { return b.Name == "" }
+
+// Branch reads the current branch and counts the commits it's ahead and
+// behind its upstream branch, configured in `branch.<name>.remote` and
+// `branch.<name>.merge`. Nothing is fetched.
+func (r *Repository) Branch() (Branch, error) {
+	ref, head, err := r.Head()
+	b := Branch{Name: strings.TrimPrefix(ref, "refs/heads/"), Head: head}
+	if err == ErrUnbornBranch {
+		return b, nil
+	}
+	if err != nil || b.Detached() {
+		return b, err
+	}
+	upstream := r.upstream(b.Name)
+	if upstream == "" {
+		return b, nil
+	}
+	h, err := r.Resolve(upstream)
+	if err == errRefNotFound {
+		return b, nil
+	}
+	if err != nil {
+		return b, err
+	}
+	b.Upstream = shortRef(upstream)
+	b.Ahead, b.Behind, err = r.aheadBehind(head, h)
+	return b, err
+}
+
+// upstream returns the reference of the upstream branch of the branch, or
+// an empty string if it has none.
+func (r *Repository) upstream(branch string) string {
+	c := r.config()
+	remote, merge := c.get("branch."+branch+".remote"), c.get("branch."+branch+".merge")
+	if remote == "" || merge == "" {
+		return ""
+	}
+	if remote == "." {
+		return merge
+	}
+	if fetch := c.get("remote." + remote + ".fetch"); fetch != "" {
+		if ref, ok := mapRefspec(fetch, merge); ok {
+			return ref
+		}
+	}
+	return "refs/remotes/" + remote + "/" + strings.TrimPrefix(merge, "refs/heads/")
+}
+
+// mapRefspec maps the remote reference to the local one with a fetch
+// refspec, like "+refs/heads/*:refs/remotes/origin/*".
+func mapRefspec(refspec, ref string) (string, bool) {
+	kv := strings.SplitN(strings.TrimPrefix(refspec, "+"), ":", 2)
+	if len(kv) != 2 {
+		return "", false
+	}
+	src, dst := kv[0], kv[1]
+	i := strings.IndexByte(src, '*')
+	if i < 0 {
+		return dst, src == ref
+	}
+	prefix, suffix := src[:i], src[i+1:]
+	if !strings.HasPrefix(ref, prefix) || !strings.HasSuffix(ref, suffix) || len(ref) < len(prefix)+len(suffix) {
+		return "", false
+	}
+	return strings.Replace(dst, "*", ref[len(prefix):len(ref)-len(suffix)], 1), true
+}
+
+// shortRef returns the name of the reference without the prefix of its
+// kind, like "origin/master" for "refs/remotes/origin/master".
+func shortRef(ref string) string {
+	for _, prefix := range []string{"refs/heads/", "refs/remotes/", "refs/tags/"} {
+		if strings.HasPrefix(ref, prefix) {
+			return ref[len(prefix):]
+		}
+	}
+	return ref
+}
+
+// aheadBehind counts the commits reachable only from `a` and only from
+// `b`. Both are walked together, newest commits first, until every queued
+// commit is reachable from both, which `pending` counts.
+func (r *Repository) aheadBehind(a, b Hash) (int, int, error) {
+	const fromA, fromB, fromBoth = 1, 2, 3
+	flags := make(map[Hash]int)
+	commits := make(map[Hash]*Commit)
+	queued := make(map[Hash]bool)
+	var queue commitQueue
+	pending := 0
+	mark := func(h Hash, flag int) error {
+		old := flags[h]
+		if old|flag == old {
+			return nil
+		}
+		flags[h] = old | flag
+		if queued[h] {
+			if flags[h] == fromBoth {
+				pending--
+			}
+			return nil
+		}
+		c, ok := commits[h]
+		if !ok {
+			var err error
+			if c, err = r.ReadCommit(h); err != nil {
+				return err
+			}
+			commits[h] = c
+		}
+		heap.Push(&queue, c)
+		queued[h] = true
+		if flags[h] != fromBoth {
+			pending++
+		}
+		return nil
+	}
+	if err := mark(a, fromA); err != nil {
+		return 0, 0, err
+	}
+	if err := mark(b, fromB); err != nil {
+		return 0, 0, err
+	}
+	for pending > 0 {
+		c := heap.Pop(&queue).(*Commit)
+		delete(queued, c.Hash)
+		if flags[c.Hash] != fromBoth {
+			pending--
+		}
+		for _, parent := range c.Parents {
+			if err := mark(parent, flags[c.Hash]); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	var ahead, behind int
+	for _, flag := range flags {
+		switch flag {
+		case fromA:
+			ahead++
+		case fromB:
+			behind++
+		}
+	}
+	return ahead, behind, nil
+}
@@ -0,0 +1,133 @@
+package git
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestBranchAheadBehind(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup leaves master checked out, tracking the branch "up".
+		setup func(f *fixture)
+	}{
+		{
+			name:  "up to date",
+			setup: func(f *fixture) {},
+		},
+		{
+			name: "ahead and behind",
+			setup: func(f *fixture) {
+				f.git("checkout", "-q", "up")
+				f.commit("up 1", "up", "1\n")
+				f.git("checkout", "-q", "master")
+				f.commit("master 1", "master", "1\n")
+				f.commit("master 2", "master", "2\n")
+			},
+		},
+		{
+			name: "merged",
+			setup: func(f *fixture) {
+				f.git("checkout", "-q", "up")
+				f.commit("up 1", "up", "1\n")
+				f.commit("up 2", "up", "2\n")
+				f.git("checkout", "-q", "master")
+				f.commit("master 1", "master", "1\n")
+				f.git("merge", "-q", "--no-edit", "up")
+				f.git("checkout", "-q", "up")
+				f.commit("up 3", "up", "3\n")
+				f.git("checkout", "-q", "master")
+			},
+		},
+		{
+			name: "criss-cross merges",
+			setup: func(f *fixture) {
+				f.git("checkout", "-q", "up")
+				f.commit("up 1", "up", "1\n")
+				f.git("checkout", "-q", "master")
+				f.commit("master 1", "master", "1\n")
+				f.git("merge", "-q", "--no-edit", "up")
+				f.git("checkout", "-q", "up")
+				f.git("merge", "-q", "--no-edit", "master~1")
+				f.commit("up 2", "up", "2\n")
+				f.git("checkout", "-q", "master")
+				f.git("merge", "-q", "--no-edit", "up")
+				f.commit("master 2", "master", "2\n")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.commit("first", "a", "a\n")
+			f.git("branch", "up")
+			f.git("branch", "-q", "--set-upstream-to=up")
+			tt.setup(f)
+			counts := strings.Fields(f.git("rev-list", "--left-right", "--count", "HEAD...up"))
+			ahead, _ := strconv.Atoi(counts[0])
+			behind, _ := strconv.Atoi(counts[1])
+
+			b, err := f.open().Branch()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.Name != "master" || b.Upstream != "up" {
+				t.Errorf("Branch() = %q...%q, want %q...%q", b.Name, b.Upstream, "master", "up")
+			}
+			if b.Ahead != ahead || b.Behind != behind {
+				t.Errorf("Branch() is ahead %d and behind %d, want ahead %d and behind %d", b.Ahead, b.Behind, ahead, behind)
+			}
+		})
+	}
+}
+
+func TestBranchWithoutUpstream(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(f *fixture)
+		branch   string
+		detached bool
+	}{
+		{"unborn branch", func(f *fixture) {}, "master", false},
+		{"no upstream", func(f *fixture) { f.commit("first", "a", "a\n") }, "master", false},
+		{"detached HEAD", func(f *fixture) {
+			f.commit("first", "a", "a\n")
+			f.git("checkout", "-q", "--detach")
+		}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			tt.setup(f)
+			b, err := f.open().Branch()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.Name != tt.branch || b.Detached() != tt.detached || b.Upstream != "" {
+				t.Errorf("Branch() = %+v, want the branch %q", b, tt.branch)
+			}
+		})
+	}
+}
+
+func TestMapRefspec(t *testing.T) {
+	tests := []struct {
+		refspec, ref string
+		want         string
+		ok           bool
+	}{
+		{"+refs/heads/*:refs/remotes/origin/*", "refs/heads/master", "refs/remotes/origin/master", true},
+		{"refs/heads/*:refs/remotes/origin/*", "refs/heads/a/b", "refs/remotes/origin/a/b", true},
+		{"+refs/heads/master:refs/remotes/origin/master", "refs/heads/master", "refs/remotes/origin/master", true},
+		{"+refs/heads/master:refs/remotes/origin/master", "refs/heads/other", "", false},
+		{"+refs/heads/*:refs/remotes/origin/*", "refs/tags/v1", "", false},
+		{"refs/heads/*", "refs/heads/master", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := mapRefspec(tt.refspec, tt.ref)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("mapRefspec(%q, %q) = %q, %v, want %q, %v", tt.refspec, tt.ref, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package git
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// config represents the variables of the configuration files, by their
+// full names, like "core.excludesfile" or "branch.master.remote". Sections
+// and keys are case-insensitive, so they're kept in lower case; subsections
+// aren't. Includes aren't followed.
+type config map[string]string
+
+// config reads the global configuration and the one of the repository,
+// which takes precedence.
+func (r *Repository) config() config {
+	c := make(config)
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		c.read(filepath.Join(dir, "git", "config"))
+	} else if home, err := os.UserHomeDir(); err == nil {
+		c.read(filepath.Join(home, ".config", "git", "config"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		c.read(filepath.Join(home, ".gitconfig"))
+	}
+	c.read(filepath.Join(r.commonDir, "config"))
+	return c
+}
+
+func (c config) get(name string) string { return c[name] }
+
+// read reads the configuration file, if it exists, overriding the
+// variables already read.
+func (c config) read(name string) {
+	file, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	var section string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = parseSection(line)
+			continue
+		}
+		if line == "" || line[0] == '#' || line[0] == ';' || section == "" {
+			continue
+		}
+		key, value := line, "true"
+		if i := strings.IndexByte(line, '='); i >= 0 {
+			key, value = strings.TrimSpace(line[:i]), parseValue(line[i+1:])
+		}
+		c[section+"."+strings.ToLower(key)] = value
+	}
+}
+
+// parseSection parses a section header, like `[core]` or
+// `[branch "master"]`.
+func parseSection(line string) string {
+	end := strings.LastIndexByte(line, ']')
+	if end < 0 {
+		return ""
+	}
+	line = line[1:end]
+	if i := strings.IndexByte(line, '"'); i >= 0 {
+		sub := strings.TrimSuffix(line[i+1:], `"`)
+		sub = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(sub)
+		return strings.ToLower(strings.TrimSpace(line[:i])) + "." + sub
+	}
+	// The deprecated `[section.subsection]` syntax.
+	if i := strings.IndexByte(line, '.'); i >= 0 {
+		return strings.ToLower(line[:i]) + "." + line[i+1:]
+	}
+	return strings.ToLower(line)
+}
+
+// parseValue parses a value, removing the quotes, the escapes and the
+// comments.
+func parseValue(s string) string {
+	var b strings.Builder
+	var quoted bool
+	s = strings.TrimSpace(s)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(s[i])
+			}
+		case (c == '#' || c == ';') && !quoted:
+			return strings.TrimSpace(b.String())
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// expandHome replaces the "~/" prefix of the path with the home directory.
+func expandHome(name string) string {
+	if !strings.HasPrefix(name, "~/") {
+		return name
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return name
+	}
+	return filepath.Join(home, name[2:])
+}
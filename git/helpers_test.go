@@ -0,0 +1,117 @@
+package git
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixture is a repository created with the `git` command for a test.
+type fixture struct {
+	t    *testing.T
+	dir  string
+	time int
+}
+
+// newFixture creates an empty repository in a temporary directory. The test
+// is skipped if the `git` command isn't available.
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	dir, err := ioutil.TempDir("", "ipe-git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	// The temporary directory may be behind a link, like in macOS.
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		t.Fatal(err)
+	}
+	f := &fixture{t: t, dir: dir, time: 1500000000}
+	f.git("init", "-q", "-b", "master")
+	f.git("config", "user.name", "Someone")
+	f.git("config", "user.email", "someone@example.com")
+	f.git("config", "commit.gpgsign", "false")
+	return f
+}
+
+// git runs the command in the repository and returns its output, without
+// the trailing new line. The test fails if the command fails.
+func (f *fixture) git(args ...string) string {
+	f.t.Helper()
+	out, err := f.run(args...)
+	if err != nil {
+		f.t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return out
+}
+
+// run runs the command in the repository, like git, but returns the error
+// for the commands expected to fail. Every commit is a minute after the
+// previous one.
+func (f *fixture) run(args ...string) (string, error) {
+	f.time += 60
+	date := fmt.Sprintf("%d +0000", f.time)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = f.dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_DATE="+date,
+		"GIT_COMMITTER_DATE="+date,
+		"GIT_CONFIG_NOSYSTEM=1",
+		"HOME="+f.dir,
+		"XDG_CONFIG_HOME="+filepath.Join(f.dir, ".config"),
+	)
+	out, err := cmd.CombinedOutput()
+	return strings.TrimSuffix(string(out), "\n"), err
+}
+
+// write writes the file, creating its directory.
+func (f *fixture) write(name, content string) {
+	f.t.Helper()
+	path := f.path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		f.t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+// commit writes the files and commits them.
+func (f *fixture) commit(message string, files ...string) {
+	f.t.Helper()
+	for i := 0; i+1 < len(files); i += 2 {
+		f.write(files[i], files[i+1])
+	}
+	f.git("add", "-A")
+	f.git("commit", "-q", "--allow-empty", "-m", message)
+}
+
+func (f *fixture) path(name string) string {
+	return filepath.Join(f.dir, filepath.FromSlash(name))
+}
+
+// open opens the repository.
+func (f *fixture) open() *Repository {
+	f.t.Helper()
+	r, err := Open(f.dir)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return r
+}
+
+func mustParseHash(t *testing.T, s string) Hash {
+	t.Helper()
+	h, err := ParseHash(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
@@ -0,0 +1,256 @@
+package git
+
+import (
+	"container/heap"
+	"path"
+)
+
+// History represents the last commit that changed each file of the HEAD
+// commit, and the newest of them under each directory.
+type History struct {
+	repo  *Repository
+	files map[string]*Commit
+	dirs  map[string]*Commit
+}
+
+// History walks the history once, newest commits first, to find the last
+// commit that changed each file of the HEAD commit. Like `git log -1 --
+// <file>`, at merges a file follows the first parent it's the same as, so
+// it's only changed by the merge if it differs from all of them.
+func (r *Repository) History() (*History, error) {
+	h := &History{repo: r, files: make(map[string]*Commit), dirs: make(map[string]*Commit)}
+	_, head, err := r.Head()
+	if err == ErrUnbornBranch {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	w := historyWalker{
+		repo:    r,
+		commits: make(map[Hash]*Commit),
+		pending: make(map[Hash]map[string]bool),
+		trees:   make(map[Hash][]treeEntry),
+		last:    h.files,
+	}
+	first, err := w.commit(head)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]treeEntry)
+	if err := r.treeFiles(first.Tree, "", files); err != nil {
+		return nil, err
+	}
+	followers := make(map[string]bool, len(files))
+	for p := range files {
+		followers[p] = true
+	}
+	w.remaining = len(followers)
+	w.follow(first, followers)
+
+	// The older commits aren't read once every file found its last one.
+	for w.remaining > 0 && w.queue.Len() > 0 {
+		c := heap.Pop(&w.queue).(*Commit)
+		followers := w.pending[c.Hash]
+		delete(w.pending, c.Hash)
+		if err := w.visit(c, followers); err != nil {
+			return nil, err
+		}
+	}
+
+	for p, c := range h.files {
+		for dir := path.Dir(p); ; dir = path.Dir(dir) {
+			if dir == "." {
+				dir = ""
+			}
+			if newest, ok := h.dirs[dir]; !ok || c.Time.After(newest.Time) {
+				h.dirs[dir] = c
+			}
+			if dir == "" {
+				break
+			}
+		}
+	}
+	return h, nil
+}
+
+// Last returns the last commit that changed the file, by its path. For
+// directories, it's the newest of the last commits of their files.
+func (h *History) Last(name string) (*Commit, bool) {
+	rel, ok := h.repo.Rel(name)
+	if !ok {
+		return nil, false
+	}
+	if c, ok := h.files[rel]; ok {
+		return c, true
+	}
+	c, ok := h.dirs[rel]
+	return c, ok
+}
+
+// historyWalker walks the history by commit time. Each queued commit has
+// the files still looking for their last commit that reached it, and
+// `remaining` counts those files.
+type historyWalker struct {
+	repo      *Repository
+	queue     commitQueue
+	commits   map[Hash]*Commit
+	pending   map[Hash]map[string]bool
+	trees     map[Hash][]treeEntry
+	last      map[string]*Commit
+	remaining int
+}
+
+func (w *historyWalker) commit(h Hash) (*Commit, error) {
+	if c, ok := w.commits[h]; ok {
+		return c, nil
+	}
+	c, err := w.repo.ReadCommit(h)
+	if err != nil {
+		return nil, err
+	}
+	w.commits[h] = c
+	return c, nil
+}
+
+// follow makes the files follow the commit, queueing it if needed.
+func (w *historyWalker) follow(c *Commit, followers map[string]bool) {
+	if len(followers) == 0 {
+		return
+	}
+	pending, ok := w.pending[c.Hash]
+	if !ok {
+		w.pending[c.Hash] = followers
+		heap.Push(&w.queue, c)
+		return
+	}
+	if len(pending) < len(followers) {
+		pending, followers = followers, pending
+		w.pending[c.Hash] = pending
+	}
+	for p := range followers {
+		pending[p] = true
+	}
+}
+
+// found sets the last commit of the file.
+func (w *historyWalker) found(p string, c *Commit) {
+	w.last[p] = c
+	w.remaining--
+}
+
+// visit finds which of the files the commit changed, and makes the others
+// follow its parents.
+func (w *historyWalker) visit(c *Commit, followers map[string]bool) error {
+	if len(c.Parents) == 0 {
+		for p := range followers {
+			w.found(p, c)
+		}
+		return nil
+	}
+	parents := make([]*Commit, len(c.Parents))
+	changed := make([]map[string]bool, len(c.Parents))
+	for i, h := range c.Parents {
+		parent, err := w.commit(h)
+		if err != nil {
+			return err
+		}
+		parents[i] = parent
+		changed[i] = make(map[string]bool)
+		if err := w.diffTrees(c.Tree, parent.Tree, "", changed[i]); err != nil {
+			return err
+		}
+	}
+	// The files the same as in the first parent are most of them, so they
+	// keep the same set, without copying it.
+	moved := make([]map[string]bool, len(parents))
+	for p := range changed[0] {
+		if !followers[p] {
+			continue
+		}
+		delete(followers, p)
+		i := 1
+		for i < len(parents) && changed[i][p] {
+			i++
+		}
+		if i == len(parents) {
+			w.found(p, c)
+			continue
+		}
+		if moved[i] == nil {
+			moved[i] = make(map[string]bool)
+		}
+		moved[i][p] = true
+	}
+	moved[0] = followers
+	for i, parent := range parents {
+		w.follow(parent, moved[i])
+	}
+	return nil
+}
+
+// diffTrees adds the files of the tree `a` that aren't the same in the tree
+// `b` to the set. Subtrees with the same name are skipped.
+func (w *historyWalker) diffTrees(a, b Hash, prefix string, changed map[string]bool) error {
+	if a == b {
+		return nil
+	}
+	entriesA, err := w.tree(a)
+	if err != nil {
+		return err
+	}
+	entriesB := map[string]treeEntry{}
+	if !b.IsZero() {
+		entries, err := w.tree(b)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			entriesB[e.name] = e
+		}
+	}
+	for _, e := range entriesA {
+		other, ok := entriesB[e.name]
+		if ok && other.hash == e.hash && other.mode == e.mode {
+			continue
+		}
+		if e.mode != modeTree {
+			changed[prefix+e.name] = true
+			continue
+		}
+		var base Hash
+		if ok && other.mode == modeTree {
+			base = other.hash
+		}
+		if err := w.diffTrees(e.hash, base, prefix+e.name+"/", changed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *historyWalker) tree(h Hash) ([]treeEntry, error) {
+	if entries, ok := w.trees[h]; ok {
+		return entries, nil
+	}
+	entries, err := w.repo.readTree(h)
+	if err != nil {
+		return nil, err
+	}
+	w.trees[h] = entries
+	return entries, nil
+}
+
+// commitQueue orders the commits from the newest to the oldest.
+type commitQueue []*Commit
+
+func (q commitQueue) Len() int            { return len(q) }
+func (q commitQueue) Less(i, j int) bool  { return q[i].CommitTime.After(q[j].CommitTime) }
+func (q commitQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x interface{}) { *q = append(*q, x.(*Commit)) }
+func (q *commitQueue) Pop() interface{} {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
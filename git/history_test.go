@@ -0,0 +1,70 @@
+package git
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	f := newFixture(t)
+	f.commit("first", "a", "a\n", "dir/b", "b\n", "dir/sub/c", "c\n", "same", "same\n")
+	f.git("checkout", "-q", "-b", "other")
+	f.commit("other", "dir/b", "other\n", "other", "other\n")
+	f.commit("same", "same", "changed in both\n")
+	f.git("checkout", "-q", "master")
+	f.commit("master", "a", "master\n", "same", "changed in both\n")
+	f.git("merge", "-q", "--no-edit", "other")
+	f.commit("after", "dir/sub/c", "after\n")
+	r := f.open()
+
+	h, err := r.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range strings.Split(f.git("ls-files"), "\n") {
+		want := f.git("log", "-1", "--format=%H", "--", path)
+		c, ok := h.Last(f.path(path))
+		if !ok {
+			t.Errorf("Last(%q) found no commit, want %s", path, want)
+			continue
+		}
+		if c.Hash.String() != want {
+			t.Errorf("Last(%q) = %s (%s), want %s", path, c.Hash, c.Message, want)
+		}
+	}
+	if c, ok := h.Last(f.path("dir")); !ok || c.Hash.String() != f.git("rev-parse", "HEAD") {
+		t.Errorf("Last(dir) = %v, want the HEAD commit", c)
+	}
+}
+
+func TestHistoryDirsByAuthorTime(t *testing.T) {
+	f := newFixture(t)
+	f.commit("first", "dir/a", "a\n", "dir/b", "b\n")
+	// The last commit is authored before the previous one, like after a
+	// rebase, so the directory shows the one authored last.
+	f.commit("authored later", "dir/a", "later\n")
+	newest := f.git("rev-parse", "HEAD")
+	f.write("dir/b", "earlier\n")
+	f.git("commit", "-q", "-a", "-m", "authored earlier", "--date=1400000000 +0000")
+
+	h, err := f.open().History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := h.Last(f.path("dir")); !ok || c.Hash.String() != newest {
+		t.Errorf("Last(dir) = %v, want %s", c, newest)
+	}
+}
+
+func TestHistoryUnbornBranch(t *testing.T) {
+	f := newFixture(t)
+	f.write("a", "a\n")
+	f.git("add", "a")
+	h, err := f.open().History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := h.Last(f.path("a")); ok {
+		t.Errorf("Last(a) = %v, want no commit", c)
+	}
+}
@@ -0,0 +1,374 @@
+package git
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Pattern represents a pattern of a gitignore file.
+type Pattern struct {
+	// base is the directory of the file, relative to the root of the
+	// matcher, with slashes.
+	base     string
+	glob     string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// ParsePattern parses a line of a gitignore file in the directory, which
+// is relative to the root of the matcher and uses slashes. It returns false
+// for blank lines and comments.
+func ParsePattern(line, base string) (Pattern, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	// Trailing spaces are ignored, unless they're escaped.
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return Pattern{}, false
+	}
+	p := Pattern{base: base}
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	// Patterns with a slash are relative to the directory of the file; the
+	// others match the name in any level below it.
+	if strings.Contains(line, "/") {
+		p.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return Pattern{}, false
+	}
+	p.glob = line
+	return p, true
+}
+
+// ReadPatterns reads the patterns of a gitignore file in the directory,
+// which is relative to the root of the matcher and uses slashes.
+// A missing file has no patterns.
+func ReadPatterns(name, base string) ([]Pattern, error) {
+	file, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var patterns []Pattern
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if p, ok := ParsePattern(scanner.Text(), base); ok {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns, scanner.Err()
+}
+
+// match reports whether the pattern matches the path, relative to the root
+// of the matcher.
+func (p Pattern) match(rel string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	if p.base != "" {
+		if !isDirPrefix(p.base, rel) {
+			return false
+		}
+		rel = rel[len(p.base)+1:]
+	}
+	if !p.anchored {
+		rel = rel[strings.LastIndexByte(rel, '/')+1:]
+	}
+	return Wildmatch(p.glob, rel)
+}
+
+// Matcher matches paths against the patterns of the files with gitignore
+// syntax in each directory, like `.gitignore`, and against patterns that
+// apply to every path. Like Git, the deeper files take precedence and the
+// last matching pattern decides. It isn't safe for concurrent use.
+type Matcher struct {
+	root     string
+	names    []string
+	patterns []Pattern
+	dirs     map[string][]Pattern
+	ignored  map[string]bool
+}
+
+// NewMatcher returns a matcher of the paths in the root directory, with the
+// patterns of the files with the names in each directory and the patterns
+// that apply to every path, which have the lowest precedence.
+func NewMatcher(root string, names []string, patterns []Pattern) *Matcher {
+	return &Matcher{
+		root:     root,
+		names:    names,
+		patterns: patterns,
+		dirs:     make(map[string][]Pattern),
+		ignored:  make(map[string]bool),
+	}
+}
+
+// Match reports whether the path is ignored, by itself or because it's in
+// an ignored directory.
+func (m *Matcher) Match(name string, isDir bool) bool {
+	rel, err := filepath.Rel(m.root, name)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	return m.matchRel(filepath.ToSlash(rel), isDir)
+}
+
+func (m *Matcher) matchRel(rel string, isDir bool) bool {
+	if i := strings.LastIndexByte(rel, '/'); i >= 0 && m.dirIgnored(rel[:i]) {
+		return true
+	}
+	return m.matchSelf(rel, isDir)
+}
+
+// dirIgnored reports whether the directory or any of its parents is
+// ignored. The result is kept for the next calls.
+func (m *Matcher) dirIgnored(dir string) bool {
+	if ignored, ok := m.ignored[dir]; ok {
+		return ignored
+	}
+	ignored := m.matchRel(dir, true)
+	m.ignored[dir] = ignored
+	return ignored
+}
+
+// matchSelf matches the path against the patterns, without its parents.
+func (m *Matcher) matchSelf(rel string, isDir bool) bool {
+	dir := path.Dir(rel)
+	for {
+		if dir == "." {
+			dir = ""
+		}
+		patterns := m.dirPatterns(dir)
+		for i := len(patterns) - 1; i >= 0; i-- {
+			if patterns[i].match(rel, isDir) {
+				return !patterns[i].negate
+			}
+		}
+		if dir == "" {
+			break
+		}
+		dir = path.Dir(dir)
+	}
+	for i := len(m.patterns) - 1; i >= 0; i-- {
+		if m.patterns[i].match(rel, isDir) {
+			return !m.patterns[i].negate
+		}
+	}
+	return false
+}
+
+// dirPatterns returns the patterns of the files in the directory, reading
+// them once. Files that can't be read are ignored.
+func (m *Matcher) dirPatterns(dir string) []Pattern {
+	if patterns, ok := m.dirs[dir]; ok {
+		return patterns
+	}
+	var patterns []Pattern
+	for _, name := range m.names {
+		ps, _ := ReadPatterns(filepath.Join(m.root, filepath.FromSlash(dir), name), dir)
+		patterns = append(patterns, ps...)
+	}
+	m.dirs[dir] = patterns
+	return patterns
+}
+
+// Wildmatch reports whether the path matches the glob, like Git does:
+// "*" and "?" don't match slashes, "**" between slashes matches any number
+// of directories, "[...]" matches a character of a class and "\" escapes
+// the next character. Paths use slashes.
+func Wildmatch(glob, name string) bool {
+	return wildmatchSegment(glob, name, true)
+}
+
+// wildmatchSegment is wildmatch, knowing whether the glob starts a segment
+// of the path, since "**" only matches directories in a segment of its own.
+func wildmatchSegment(glob, name string, start bool) bool {
+	for len(glob) > 0 {
+		switch glob[0] {
+		case '*':
+			if start && strings.HasPrefix(glob, "**") && (len(glob) == 2 || glob[2] == '/') {
+				rest := strings.TrimPrefix(glob[2:], "/")
+				if rest == "" {
+					return true
+				}
+				for {
+					if wildmatchSegment(rest, name, true) {
+						return true
+					}
+					i := strings.IndexByte(name, '/')
+					if i < 0 {
+						return false
+					}
+					name = name[i+1:]
+				}
+			}
+			glob = strings.TrimLeft(glob, "*")
+			for i := 0; i <= len(name); i++ {
+				if wildmatchSegment(glob, name[i:], false) {
+					return true
+				}
+				if i < len(name) && name[i] == '/' {
+					return false
+				}
+			}
+			return false
+		case '?':
+			if name == "" || name[0] == '/' {
+				return false
+			}
+			glob, name = glob[1:], name[1:]
+		case '[':
+			if name == "" || name[0] == '/' {
+				return false
+			}
+			matched, width, ok := matchClass(glob, name[0])
+			if !ok {
+				// An unclosed bracket is a literal.
+				if name[0] != '[' {
+					return false
+				}
+				glob, name, start = glob[1:], name[1:], false
+				continue
+			}
+			if !matched {
+				return false
+			}
+			glob, name = glob[width:], name[1:]
+		case '\\':
+			if len(glob) > 1 {
+				glob = glob[1:]
+			}
+			fallthrough
+		default:
+			if name == "" || name[0] != glob[0] {
+				return false
+			}
+			start = glob[0] == '/'
+			glob, name = glob[1:], name[1:]
+			continue
+		}
+		start = false
+	}
+	return name == ""
+}
+
+// matchClass matches the character against the class at the start of the
+// glob, like "[a-z]" or "[!0-9]". It returns the length of the class and
+// false if it isn't closed.
+func matchClass(glob string, c byte) (bool, int, bool) {
+	i := 1
+	negate := i < len(glob) && (glob[i] == '!' || glob[i] == '^')
+	if negate {
+		i++
+	}
+	matched := false
+	for first := true; i < len(glob); first = false {
+		if glob[i] == ']' && !first {
+			return matched != negate, i + 1, true
+		}
+		lo := glob[i]
+		if lo == '\\' && i+1 < len(glob) {
+			i++
+			lo = glob[i]
+		}
+		hi := lo
+		if i+2 < len(glob) && glob[i+1] == '-' && glob[i+2] != ']' {
+			hi = glob[i+2]
+			i += 2
+		}
+		if lo <= c && c <= hi {
+			matched = true
+		}
+		i++
+	}
+	return false, 0, false
+}
+
+// Ignore tells the files a repository ignores: the ones matching the
+// patterns of the `.gitignore` files, of `.git/info/exclude` and of the
+// global excludes file. Tracked files are never ignored.
+// It isn't safe for concurrent use.
+type Ignore struct {
+	repo    *Repository
+	matcher *Matcher
+	tracked map[string]bool
+	dirs    map[string]bool
+}
+
+// Ignore reads the patterns and the tracked files of the repository.
+func (r *Repository) Ignore() (*Ignore, error) {
+	entries, _, err := r.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	return r.newIgnore(entries), nil
+}
+
+func (r *Repository) newIgnore(entries []indexEntry) *Ignore {
+	var patterns []Pattern
+	if name := r.excludesFile(); name != "" {
+		ps, _ := ReadPatterns(name, "")
+		patterns = append(patterns, ps...)
+	}
+	ps, _ := ReadPatterns(filepath.Join(r.commonDir, "info", "exclude"), "")
+	patterns = append(patterns, ps...)
+	ig := &Ignore{
+		repo:    r,
+		matcher: NewMatcher(r.WorkTree, []string{".gitignore"}, patterns),
+		tracked: make(map[string]bool),
+		dirs:    make(map[string]bool),
+	}
+	for _, e := range entries {
+		ig.tracked[e.path] = true
+		for dir := path.Dir(e.path); dir != "." && !ig.dirs[dir]; dir = path.Dir(dir) {
+			ig.dirs[dir] = true
+		}
+	}
+	return ig
+}
+
+// excludesFile returns the path of the global excludes file, configured
+// in `core.excludesFile` or in the default location.
+func (r *Repository) excludesFile() string {
+	if name := r.config().get("core.excludesfile"); name != "" {
+		return expandHome(name)
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "git", "ignore")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".config", "git", "ignore")
+	}
+	return ""
+}
+
+// Match reports whether the file, by its path, is ignored.
+func (ig *Ignore) Match(name string, isDir bool) bool {
+	rel, ok := ig.repo.Rel(name)
+	if !ok {
+		return false
+	}
+	return ig.matchRel(rel, isDir)
+}
+
+func (ig *Ignore) matchRel(rel string, isDir bool) bool {
+	if rel == "" || isGitDir(rel) || ig.tracked[rel] || (isDir && ig.dirs[rel]) {
+		return false
+	}
+	return ig.matcher.matchRel(rel, isDir)
+}
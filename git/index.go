@@ -0,0 +1,134 @@
+package git
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The modes of the files in trees and in the index.
+const (
+	modeTree    = 0040000
+	modeFile    = 0100644
+	modeExec    = 0100755
+	modeSymlink = 0120000
+	modeGitlink = 0160000
+	modeType    = 0170000
+)
+
+var errInvalidIndex = errors.New("invalid index")
+
+// indexEntry represents an entry of the index.
+type indexEntry struct {
+	path         string
+	hash         Hash
+	mode         uint32
+	stage        int
+	size         uint32
+	modTime      time.Time
+	intentToAdd  bool
+	skipWorktree bool
+}
+
+// readIndex reads the entries of the index, versions 2, 3 and 4.
+// The extensions are ignored.
+func (r *Repository) readIndex() ([]indexEntry, time.Time, error) {
+	file, err := os.Open(filepath.Join(r.gitDir, "index"))
+	if os.IsNotExist(err) {
+		return nil, time.Time{}, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	reader := bufio.NewReader(file)
+	var header struct {
+		Signature [4]byte
+		Version   uint32
+		Count     uint32
+	}
+	if err := binary.Read(reader, binary.BigEndian, &header); err != nil {
+		return nil, time.Time{}, err
+	}
+	if string(header.Signature[:]) != "DIRC" || header.Version < 2 || header.Version > 4 {
+		return nil, time.Time{}, errors.New("unsupported index")
+	}
+	entries := make([]indexEntry, 0, header.Count)
+	var previous string
+	for i := uint32(0); i < header.Count; i++ {
+		var fixed struct {
+			CTime, CTimeNano uint32
+			MTime, MTimeNano uint32
+			Dev, Ino         uint32
+			Mode             uint32
+			UID, GID         uint32
+			Size             uint32
+			Hash             Hash
+			Flags            uint16
+		}
+		if err := binary.Read(reader, binary.BigEndian, &fixed); err != nil {
+			return nil, time.Time{}, errInvalidIndex
+		}
+		length := 62
+		e := indexEntry{
+			hash:    fixed.Hash,
+			mode:    fixed.Mode,
+			stage:   int(fixed.Flags >> 12 & 3),
+			size:    fixed.Size,
+			modTime: time.Unix(int64(fixed.MTime), int64(fixed.MTimeNano)),
+		}
+		if fixed.Flags&0x4000 != 0 && header.Version >= 3 {
+			var extended uint16
+			if err := binary.Read(reader, binary.BigEndian, &extended); err != nil {
+				return nil, time.Time{}, errInvalidIndex
+			}
+			e.skipWorktree = extended&0x4000 != 0
+			e.intentToAdd = extended&0x2000 != 0
+			length += 2
+		}
+		if header.Version == 4 {
+			// The name is the previous one, without its last bytes, plus
+			// a suffix. There's no padding.
+			strip, err := readOffset(reader)
+			if err != nil || strip > int64(len(previous)) {
+				return nil, time.Time{}, errInvalidIndex
+			}
+			suffix, err := reader.ReadBytes(0)
+			if err != nil {
+				return nil, time.Time{}, errInvalidIndex
+			}
+			e.path = previous[:len(previous)-int(strip)] + string(suffix[:len(suffix)-1])
+		} else {
+			name, err := reader.ReadBytes(0)
+			if err != nil {
+				return nil, time.Time{}, errInvalidIndex
+			}
+			e.path = string(name[:len(name)-1])
+			// The entry is padded with 1 to 8 NULs, one of them already read.
+			length += len(name)
+			if padding := (8 - length%8) % 8; padding > 0 {
+				if _, err := io.CopyN(ioutil.Discard, reader, int64(padding)); err != nil {
+					return nil, time.Time{}, errInvalidIndex
+				}
+			}
+		}
+		previous = e.path
+		entries = append(entries, e)
+	}
+	return entries, info.ModTime(), nil
+}
+
+// isDirPrefix reports whether the path is inside the directory. The root
+// directory is the empty path.
+func isDirPrefix(dir, path string) bool {
+	return dir == "" || (len(path) > len(dir) && path[len(dir)] == '/' && path[:len(dir)] == dir)
+}
@@ -0,0 +1,135 @@
+package git
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReadIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		// extended adds entries with the flags that need version 3.
+		extended bool
+	}{
+		{"version 2", 2, false},
+		{"version 3", 3, true},
+		{"version 4", 4, false},
+		{"version 4 with extended flags", 4, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.commit("first",
+				"a", "a\n",
+				"dir/file", "file\n",
+				"dir/file2", "file2\n",
+				"dir/sub/deep/file", "deep\n",
+				"directory/file", "other prefix\n",
+			)
+			f.write("exec", "#!/bin/sh\n")
+			f.git("add", "exec")
+			f.git("update-index", "--chmod=+x", "exec")
+			if tt.extended {
+				f.git("update-index", "--skip-worktree", "dir/file2")
+				f.write("new", "new\n")
+				f.git("add", "-N", "new")
+			}
+			f.git("update-index", "--index-version", strconv.Itoa(tt.version))
+			if got := indexVersion(t, f); got != tt.version {
+				t.Fatalf("the index has version %d, want %d", got, tt.version)
+			}
+
+			entries, _, err := f.open().readIndex()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := strings.Split(f.git("ls-files", "-s"), "\n")
+			if len(entries) != len(want) {
+				t.Fatalf("readIndex() has %d entries, want %d", len(entries), len(want))
+			}
+			for i, e := range entries {
+				got := strconv.FormatUint(uint64(e.mode), 8) + " " + e.hash.String() + " " + strconv.Itoa(e.stage) + "\t" + e.path
+				if got != want[i] {
+					t.Errorf("entry %d = %q, want %q", i, got, want[i])
+				}
+				if e.skipWorktree != (tt.extended && e.path == "dir/file2") {
+					t.Errorf("entry %q skipWorktree = %v", e.path, e.skipWorktree)
+				}
+				if e.intentToAdd != (tt.extended && e.path == "new") {
+					t.Errorf("entry %q intentToAdd = %v", e.path, e.intentToAdd)
+				}
+			}
+		})
+	}
+}
+
+func TestReadIndexConflicts(t *testing.T) {
+	f := newFixture(t)
+	f.commit("base", "both", "base\n", "ours", "base\n")
+	f.git("checkout", "-q", "-b", "other")
+	f.commit("theirs", "both", "theirs\n", "added", "theirs\n")
+	f.git("checkout", "-q", "master")
+	f.commit("ours", "both", "ours\n", "added", "ours\n")
+	if _, err := f.run("merge", "-q", "other"); err == nil {
+		t.Fatal("the merge succeeded without conflicts")
+	}
+
+	entries, _, err := f.open().readIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stages := make(map[string][]int)
+	for _, e := range entries {
+		stages[e.path] = append(stages[e.path], e.stage)
+	}
+	want := map[string]string{
+		"added": "[2 3]",
+		"both":  "[1 2 3]",
+		"ours":  "[0]",
+	}
+	for path, w := range want {
+		if got := fmtInts(stages[path]); got != w {
+			t.Errorf("stages of %q = %s, want %s", path, got, w)
+		}
+	}
+}
+
+func fmtInts(ns []int) string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = strconv.Itoa(n)
+	}
+	return "[" + strings.Join(s, " ") + "]"
+}
+
+// indexVersion reads the version in the header of the index.
+func indexVersion(t *testing.T, f *fixture) int {
+	t.Helper()
+	b, err := ioutil.ReadFile(f.path(".git/index"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int(binary.BigEndian.Uint32(b[4:]))
+}
+
+func TestIsDirPrefix(t *testing.T) {
+	tests := []struct {
+		dir, path string
+		want      bool
+	}{
+		{"", "a", true},
+		{"a", "a/b", true},
+		{"a", "a", false},
+		{"a", "ab/c", false},
+		{"a/b", "a/b/c/d", true},
+	}
+	for _, tt := range tests {
+		if got := isDirPrefix(tt.dir, tt.path); got != tt.want {
+			t.Errorf("isDirPrefix(%q, %q) = %v, want %v", tt.dir, tt.path, got, tt.want)
+		}
+	}
+}
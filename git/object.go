@@ -0,0 +1,247 @@
+package git
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrObjectNotFound is returned when an object isn't in the repository.
+var ErrObjectNotFound = errors.New("object not found")
+
+// Hash represents the SHA-1 name of an object.
+type Hash [20]byte
+
+// ParseHash parses the hexadecimal name of an object.
+func ParseHash(s string) (Hash, error) {
+	var h Hash
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != len(h) {
+		return h, fmt.Errorf("invalid object name %q", s)
+	}
+	copy(h[:], b)
+	return h, nil
+}
+
+// String returns the hexadecimal name of the object.
+func (h Hash) String() string { return hex.EncodeToString(h[:]) }
+
+// IsZero reports whether the hash is all zeros.
+func (h Hash) IsZero() bool { return h == Hash{} }
+
+// objectType represents the type of an object, with the same numbers the
+// packs use.
+type objectType int
+
+const (
+	commitObject objectType = 1
+	treeObject   objectType = 2
+	blobObject   objectType = 3
+	tagObject    objectType = 4
+	ofsDelta     objectType = 6
+	refDelta     objectType = 7
+)
+
+var objectTypeNames = map[string]objectType{
+	"commit": commitObject,
+	"tree":   treeObject,
+	"blob":   blobObject,
+	"tag":    tagObject,
+}
+
+// hashBlob returns the name the content would have as a blob.
+func hashBlob(content []byte) Hash {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write(content)
+	var hash Hash
+	copy(hash[:], h.Sum(nil))
+	return hash
+}
+
+// readObject returns the type and the content of the object, loose or
+// packed.
+func (r *Repository) readObject(h Hash) (objectType, []byte, error) {
+	typ, content, err := r.readLooseObject(h)
+	if !os.IsNotExist(err) {
+		return typ, content, err
+	}
+	packs, err := r.loadPacks()
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, p := range packs {
+		if offset, ok := p.find(h); ok {
+			return p.readObject(r, offset)
+		}
+	}
+	return 0, nil, ErrObjectNotFound
+}
+
+func (r *Repository) readLooseObject(h Hash) (objectType, []byte, error) {
+	name := h.String()
+	file, err := os.Open(filepath.Join(r.commonDir, "objects", name[:2], name[2:]))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.Close()
+	z, err := zlib.NewReader(file)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer z.Close()
+	b, err := ioutil.ReadAll(z)
+	if err != nil {
+		return 0, nil, err
+	}
+	i := bytes.IndexByte(b, 0)
+	if i < 0 {
+		return 0, nil, fmt.Errorf("invalid object %s", name)
+	}
+	header := strings.Fields(string(b[:i]))
+	if len(header) != 2 {
+		return 0, nil, fmt.Errorf("invalid object %s", name)
+	}
+	typ, ok := objectTypeNames[header[0]]
+	if !ok {
+		return 0, nil, fmt.Errorf("invalid object %s", name)
+	}
+	return typ, b[i+1:], nil
+}
+
+// treeEntry represents an entry of a tree object.
+type treeEntry struct {
+	mode uint32
+	name string
+	hash Hash
+}
+
+// readTree returns the entries of the tree object.
+func (r *Repository) readTree(h Hash) ([]treeEntry, error) {
+	typ, b, err := r.readObject(h)
+	if err != nil {
+		return nil, err
+	}
+	if typ != treeObject {
+		return nil, fmt.Errorf("object %s isn't a tree", h)
+	}
+	var entries []treeEntry
+	for len(b) > 0 {
+		sp := bytes.IndexByte(b, ' ')
+		nul := bytes.IndexByte(b, 0)
+		if sp < 0 || nul < sp || nul+21 > len(b) {
+			return nil, fmt.Errorf("invalid tree %s", h)
+		}
+		mode, err := strconv.ParseUint(string(b[:sp]), 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tree %s", h)
+		}
+		e := treeEntry{mode: uint32(mode), name: string(b[sp+1 : nul])}
+		copy(e.hash[:], b[nul+1:nul+21])
+		entries = append(entries, e)
+		b = b[nul+21:]
+	}
+	return entries, nil
+}
+
+// treeFiles adds every file of the tree, recursively, to the map, by their
+// path with the prefix.
+func (r *Repository) treeFiles(h Hash, prefix string, files map[string]treeEntry) error {
+	entries, err := r.readTree(h)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		path := prefix + e.name
+		if e.mode == modeTree {
+			if err := r.treeFiles(e.hash, path+"/", files); err != nil {
+				return err
+			}
+			continue
+		}
+		files[path] = e
+	}
+	return nil
+}
+
+// Commit represents a commit object. The time is the author's, and the
+// commit time is the committer's.
+type Commit struct {
+	Hash       Hash
+	Tree       Hash
+	Parents    []Hash
+	Author     string
+	Email      string
+	Time       time.Time
+	Committer  string
+	CommitTime time.Time
+	Message    string
+}
+
+// ReadCommit reads the commit object.
+func (r *Repository) ReadCommit(h Hash) (*Commit, error) {
+	typ, b, err := r.readObject(h)
+	if err != nil {
+		return nil, err
+	}
+	if typ != commitObject {
+		return nil, fmt.Errorf("object %s isn't a commit", h)
+	}
+	c := &Commit{Hash: h}
+	text := string(b)
+	if i := strings.Index(text, "\n\n"); i >= 0 {
+		c.Message = text[i+2:]
+		text = text[:i]
+	}
+	for _, line := range strings.Split(text, "\n") {
+		kv := strings.SplitN(line, " ", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "tree":
+			c.Tree, err = ParseHash(kv[1])
+		case "parent":
+			var parent Hash
+			parent, err = ParseHash(kv[1])
+			c.Parents = append(c.Parents, parent)
+		case "author":
+			c.Author, c.Email, c.Time = parseSignature(kv[1])
+		case "committer":
+			c.Committer, _, c.CommitTime = parseSignature(kv[1])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// parseSignature parses a signature like "Name <email> 1500000000 -0300".
+func parseSignature(s string) (string, string, time.Time) {
+	lt, gt := strings.IndexByte(s, '<'), strings.LastIndexByte(s, '>')
+	if lt < 0 || gt < lt {
+		return s, "", time.Time{}
+	}
+	name, email := strings.TrimSpace(s[:lt]), s[lt+1:gt]
+	fields := strings.Fields(s[gt+1:])
+	if len(fields) != 2 {
+		return name, email, time.Time{}
+	}
+	sec, _ := strconv.ParseInt(fields[0], 10, 64)
+	t := time.Unix(sec, 0)
+	if tz, err := strconv.Atoi(fields[1]); err == nil {
+		offset := (tz/100*60 + tz%100) * 60
+		t = t.In(time.FixedZone(fields[1], offset))
+	}
+	return name, email, t
+}
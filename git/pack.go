@@ -0,0 +1,276 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// pack represents a pack file and its index, version 2.
+type pack struct {
+	path    string
+	hashes  []Hash
+	offsets []int64
+}
+
+// maxDeltaChain limits the chain of deltas of an object, so corrupt packs
+// can't loop forever.
+const maxDeltaChain = 10000
+
+var errInvalidPack = errors.New("invalid pack")
+
+// loadPacks returns the indexes of all the packs, reading them once. If
+// any of them can't be read, they're read again in the next call.
+func (r *Repository) loadPacks() ([]*pack, error) {
+	r.packsMutex.Lock()
+	defer r.packsMutex.Unlock()
+	if r.packs != nil {
+		return r.packs, nil
+	}
+	idxs, err := filepath.Glob(filepath.Join(r.commonDir, "objects", "pack", "*.idx"))
+	if err != nil {
+		return nil, err
+	}
+	packs := make([]*pack, 0, len(idxs))
+	for _, idx := range idxs {
+		p, err := readPackIndex(idx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", idx, err)
+		}
+		packs = append(packs, p)
+	}
+	r.packs = packs
+	return packs, nil
+}
+
+func readPackIndex(path string) (*pack, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	const header = 8 + 256*4
+	if len(b) < header || !bytes.Equal(b[:4], []byte("\377tOc")) || binary.BigEndian.Uint32(b[4:]) != 2 {
+		return nil, errors.New("unsupported pack index")
+	}
+	n := int(binary.BigEndian.Uint32(b[header-4:]))
+	hashesAt := header
+	offsetsAt := hashesAt + n*20 + n*4
+	largeAt := offsetsAt + n*4
+	if len(b) < largeAt {
+		return nil, errInvalidPack
+	}
+	p := &pack{
+		path:    strings.TrimSuffix(path, ".idx") + ".pack",
+		hashes:  make([]Hash, n),
+		offsets: make([]int64, n),
+	}
+	for i := 0; i < n; i++ {
+		copy(p.hashes[i][:], b[hashesAt+i*20:])
+		offset := binary.BigEndian.Uint32(b[offsetsAt+i*4:])
+		if offset&0x80000000 == 0 {
+			p.offsets[i] = int64(offset)
+			continue
+		}
+		at := largeAt + int(offset&0x7fffffff)*8
+		if len(b) < at+8 {
+			return nil, errInvalidPack
+		}
+		p.offsets[i] = int64(binary.BigEndian.Uint64(b[at:]))
+	}
+	return p, nil
+}
+
+// find returns the offset of the object in the pack.
+func (p *pack) find(h Hash) (int64, bool) {
+	i := sort.Search(len(p.hashes), func(i int) bool {
+		return bytes.Compare(p.hashes[i][:], h[:]) >= 0
+	})
+	if i < len(p.hashes) && p.hashes[i] == h {
+		return p.offsets[i], true
+	}
+	return 0, false
+}
+
+// readObject reads the object at the offset, applying its deltas.
+func (p *pack) readObject(r *Repository, offset int64) (objectType, []byte, error) {
+	file, err := os.Open(p.path)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.Close()
+
+	var deltas [][]byte
+	for len(deltas) < maxDeltaChain {
+		typ, content, base, baseHash, err := readPackEntry(file, offset)
+		if err != nil {
+			return 0, nil, err
+		}
+		switch typ {
+		case ofsDelta:
+			deltas = append(deltas, content)
+			offset = base
+			continue
+		case refDelta:
+			deltas = append(deltas, content)
+			if baseOffset, ok := p.find(baseHash); ok {
+				offset = baseOffset
+				continue
+			}
+			// The base is in another pack or loose.
+			typ, content, err = r.readObject(baseHash)
+			if err != nil {
+				return 0, nil, err
+			}
+		}
+		for i := len(deltas) - 1; i >= 0; i-- {
+			if content, err = applyDelta(content, deltas[i]); err != nil {
+				return 0, nil, err
+			}
+		}
+		return typ, content, nil
+	}
+	return 0, nil, errInvalidPack
+}
+
+// readPackEntry reads the entry at the offset. For deltas, it returns the
+// offset or the name of the base object.
+func readPackEntry(file *os.File, offset int64) (objectType, []byte, int64, Hash, error) {
+	var baseHash Hash
+	reader := bufio.NewReader(io.NewSectionReader(file, offset, 1<<62))
+	c, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, 0, baseHash, err
+	}
+	typ := objectType(c >> 4 & 7)
+	size := int64(c & 15)
+	for shift := uint(4); c&0x80 != 0; shift += 7 {
+		if c, err = reader.ReadByte(); err != nil {
+			return 0, nil, 0, baseHash, err
+		}
+		size |= int64(c&0x7f) << shift
+	}
+	var base int64
+	switch typ {
+	case ofsDelta:
+		distance, err := readOffset(reader)
+		if err != nil {
+			return 0, nil, 0, baseHash, err
+		}
+		base = offset - distance
+	case refDelta:
+		if _, err := io.ReadFull(reader, baseHash[:]); err != nil {
+			return 0, nil, 0, baseHash, err
+		}
+	case commitObject, treeObject, blobObject, tagObject:
+	default:
+		return 0, nil, 0, baseHash, errInvalidPack
+	}
+	z, err := zlib.NewReader(reader)
+	if err != nil {
+		return 0, nil, 0, baseHash, err
+	}
+	defer z.Close()
+	// The size comes from the pack, so the buffer only grows as the data is
+	// really there, instead of trusting it for a single allocation. One more
+	// byte is read to tell if there's more data than the size.
+	var content bytes.Buffer
+	if _, err := content.ReadFrom(io.LimitReader(z, size+1)); err != nil {
+		return 0, nil, 0, baseHash, err
+	}
+	if int64(content.Len()) != size {
+		return 0, nil, 0, baseHash, errInvalidPack
+	}
+	return typ, content.Bytes(), base, baseHash, nil
+}
+
+// readOffset reads a variable-length offset, in which every continuation
+// byte also adds one, so there's only one way to encode each number.
+// The same encoding is used for the names in the index, version 4.
+func readOffset(reader io.ByteReader) (int64, error) {
+	c, err := reader.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	n := int64(c & 0x7f)
+	for c&0x80 != 0 {
+		if c, err = reader.ReadByte(); err != nil {
+			return 0, err
+		}
+		n = (n+1)<<7 | int64(c&0x7f)
+	}
+	return n, nil
+}
+
+// applyDelta builds an object from its base and a delta, which is a series
+// of instructions to copy parts of the base or to insert new data.
+func applyDelta(base, delta []byte) ([]byte, error) {
+	reader := bytes.NewReader(delta)
+	baseSize, err := binary.ReadUvarint(reader)
+	if err != nil || baseSize != uint64(len(base)) {
+		return nil, errInvalidPack
+	}
+	size, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, errInvalidPack
+	}
+	// The size comes from the delta, so it isn't trusted for the
+	// allocation: copies can repeat the base, but the result usually isn't
+	// bigger than both together, and it grows if needed.
+	capacity := size
+	if limit := uint64(len(base) + len(delta)); capacity > limit {
+		capacity = limit
+	}
+	result := make([]byte, 0, capacity)
+	for {
+		op, err := reader.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if op&0x80 == 0 {
+			if op == 0 {
+				return nil, errInvalidPack
+			}
+			data := make([]byte, op)
+			if _, err := io.ReadFull(reader, data); err != nil || uint64(len(result)+len(data)) > size {
+				return nil, errInvalidPack
+			}
+			result = append(result, data...)
+			continue
+		}
+		var offset, length uint32
+		for i := uint(0); i < 7; i++ {
+			if op&(1<<i) == 0 {
+				continue
+			}
+			c, err := reader.ReadByte()
+			if err != nil {
+				return nil, errInvalidPack
+			}
+			if i < 4 {
+				offset |= uint32(c) << (8 * i)
+			} else {
+				length |= uint32(c) << (8 * (i - 4))
+			}
+		}
+		if length == 0 {
+			length = 0x10000
+		}
+		if uint64(offset)+uint64(length) > uint64(len(base)) || uint64(len(result))+uint64(length) > size {
+			return nil, errInvalidPack
+		}
+		result = append(result, base[offset:offset+length]...)
+	}
+	if uint64(len(result)) != size {
+		return nil, errInvalidPack
+	}
+	return result, nil
+}
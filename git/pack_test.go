@@ -0,0 +1,340 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// catObject is an object as the `git` command reads it.
+type catObject struct {
+	typ     string
+	content []byte
+}
+
+// catFile returns all the objects of the repository, as the `git` command
+// reads them.
+func (f *fixture) catFile() map[Hash]catObject {
+	f.t.Helper()
+	objects := make(map[Hash]catObject)
+	out := f.git("cat-file", "--batch-all-objects", "--batch")
+	reader := bufio.NewReader(strings.NewReader(out + "\n"))
+	for {
+		header, err := reader.ReadString('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.t.Fatal(err)
+		}
+		fields := strings.Fields(header)
+		size, _ := strconv.Atoi(fields[2])
+		// The content is followed by a new line.
+		content := make([]byte, size+1)
+		if _, err := io.ReadFull(reader, content); err != nil {
+			f.t.Fatal(err)
+		}
+		objects[mustParseHash(f.t, fields[0])] = catObject{fields[1], content[:size]}
+	}
+	return objects
+}
+
+// deltaFixture creates a repository with a file changed in many commits, so
+// its versions are stored as a chain of deltas when packed. Each version
+// slides a window of lines, so it's only similar to the next ones.
+func deltaFixture(t *testing.T) *fixture {
+	f := newFixture(t)
+	for i := 0; i < 30; i++ {
+		var content strings.Builder
+		for line := i; line < i+20; line++ {
+			fmt.Fprintf(&content, "line %d of a file long enough to be worth a delta\n", line)
+		}
+		f.commit(fmt.Sprintf("commit %d", i), "file", content.String(), "dir/other", strconv.Itoa(i%3))
+	}
+	return f
+}
+
+func TestReadObject(t *testing.T) {
+	tests := []struct {
+		name   string
+		repack []string
+		deltas objectType
+	}{
+		{name: "loose"},
+		{name: "offset deltas", repack: []string{"repack", "-adf", "--depth=50", "--window=50"}, deltas: ofsDelta},
+		{name: "reference deltas", repack: []string{"-c", "repack.useDeltaBaseOffset=false", "repack", "-adf", "--depth=50", "--window=50"}, deltas: refDelta},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := deltaFixture(t)
+			if tt.repack != nil {
+				f.git(tt.repack...)
+			}
+			r := f.open()
+			if tt.deltas != 0 {
+				if n := countPackEntries(t, r, tt.deltas); n == 0 {
+					t.Fatalf("the pack has no entries of type %d", tt.deltas)
+				}
+				if depth := maxDeltaDepth(t, f); depth < 2 {
+					t.Fatalf("the longest delta chain has %d deltas, want at least 2", depth)
+				}
+			}
+			for h, want := range f.catFile() {
+				typ, content, err := r.readObject(h)
+				if err != nil {
+					t.Errorf("readObject(%s) error = %v", h, err)
+					continue
+				}
+				if typ != objectTypeNames[want.typ] {
+					t.Errorf("readObject(%s) type = %d, want %s", h, typ, want.typ)
+				}
+				if !bytes.Equal(content, want.content) {
+					t.Errorf("readObject(%s) content = %q, want %q", h, content, want.content)
+				}
+			}
+		})
+	}
+}
+
+// countPackEntries counts the entries of the type in all the packs.
+func countPackEntries(t *testing.T, r *Repository, typ objectType) int {
+	t.Helper()
+	packs, err := r.loadPacks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	for _, p := range packs {
+		file, err := os.Open(p.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, offset := range p.offsets {
+			entryType, _, _, _, err := readPackEntry(file, offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entryType == typ {
+				n++
+			}
+		}
+		file.Close()
+	}
+	return n
+}
+
+// maxDeltaDepth returns the longest chain of deltas in the packs, as told
+// by `git verify-pack`.
+func maxDeltaDepth(t *testing.T, f *fixture) int {
+	t.Helper()
+	idxs, _ := filepath.Glob(f.path(".git/objects/pack/*.idx"))
+	var depth int
+	for _, idx := range idxs {
+		for _, line := range strings.Split(f.git("verify-pack", "-v", idx), "\n") {
+			// Deltas have the depth and the base after the offset.
+			if fields := strings.Fields(line); len(fields) == 7 {
+				if d, _ := strconv.Atoi(fields[5]); d > depth {
+					depth = d
+				}
+			}
+		}
+	}
+	return depth
+}
+
+func TestLoadPacksInvalidIndex(t *testing.T) {
+	f := deltaFixture(t)
+	f.git("repack", "-adf")
+	head := mustParseHash(t, f.git("rev-parse", "HEAD"))
+	invalid := f.path(".git/objects/pack/pack-invalid.idx")
+	if err := ioutil.WriteFile(invalid, []byte("invalid"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := f.open()
+
+	// The packs aren't kept partially read, so every call fails until the
+	// index is fixed.
+	for i := 0; i < 2; i++ {
+		if _, _, err := r.readObject(head); err == nil {
+			t.Fatalf("readObject() call %d succeeded with an invalid pack index", i+1)
+		}
+	}
+	if err := os.Remove(invalid); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.readObject(head); err != nil {
+		t.Fatalf("readObject() error = %v after removing the invalid pack index", err)
+	}
+}
+
+func TestReadPackEntrySize(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int64
+		content string
+		err     bool
+	}{
+		{"exact size", 3, "abc", false},
+		{"more content than the size", 2, "abc", true},
+		{"less content than the size", 4, "abc", true},
+		{"huge size", 1 << 40, "abc", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entry bytes.Buffer
+			// The type and the first 4 bits of the size, then 7 bits per
+			// byte, while the high bit is set.
+			c := byte(blobObject)<<4 | byte(tt.size&15)
+			for size := tt.size >> 4; ; size >>= 7 {
+				if size == 0 {
+					entry.WriteByte(c)
+					break
+				}
+				entry.WriteByte(c | 0x80)
+				c = byte(size & 0x7f)
+			}
+			z := zlib.NewWriter(&entry)
+			z.Write([]byte(tt.content))
+			z.Close()
+			file := tempFile(t, entry.Bytes())
+
+			typ, content, _, _, err := readPackEntry(file, 0)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("readPackEntry() = %q, want an error", content)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if typ != blobObject || string(content) != tt.content {
+				t.Errorf("readPackEntry() = %d, %q, want %d, %q", typ, content, blobObject, tt.content)
+			}
+		})
+	}
+}
+
+func tempFile(t *testing.T, content []byte) *os.File {
+	t.Helper()
+	file, err := ioutil.TempFile("", "ipe-pack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		file.Close()
+		os.Remove(file.Name())
+	})
+	if _, err := file.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestReadOffset(t *testing.T) {
+	tests := []struct {
+		in   []byte
+		want int64
+	}{
+		{[]byte{0x00}, 0},
+		{[]byte{0x7f}, 127},
+		{[]byte{0x80, 0x00}, 128},
+		{[]byte{0x80, 0x7f}, 255},
+		{[]byte{0x81, 0x00}, 256},
+		{[]byte{0xff, 0x7f}, 16511},
+		{[]byte{0x80, 0x80, 0x00}, 16512},
+	}
+	for _, tt := range tests {
+		got, err := readOffset(bytes.NewReader(tt.in))
+		if err != nil || got != tt.want {
+			t.Errorf("readOffset(%x) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := readOffset(bytes.NewReader([]byte{0x80})); err == nil {
+		t.Error("readOffset() of a truncated offset succeeded")
+	}
+}
+
+func TestApplyDelta(t *testing.T) {
+	base := []byte("hello, world")
+	tests := []struct {
+		name  string
+		delta []byte
+		want  string
+		err   bool
+	}{
+		{
+			name: "copy and insert",
+			// Copies 5 bytes from offset 0, inserts "!" and copies 5 bytes
+			// from offset 7.
+			delta: []byte{12, 11, 0x91, 0, 5, 1, '!', 0x91, 7, 5},
+			want:  "hello!world",
+		},
+		{
+			name:  "insert only",
+			delta: []byte{12, 3, 3, 'a', 'b', 'c'},
+			want:  "abc",
+		},
+		{
+			name:  "wrong base size",
+			delta: []byte{11, 3, 3, 'a', 'b', 'c'},
+			err:   true,
+		},
+		{
+			name:  "copy past the base",
+			delta: []byte{12, 5, 0x91, 10, 5},
+			err:   true,
+		},
+		{
+			name:  "reserved instruction",
+			delta: []byte{12, 1, 0},
+			err:   true,
+		},
+		{
+			name:  "wrong result size",
+			delta: []byte{12, 4, 3, 'a', 'b', 'c'},
+			err:   true,
+		},
+		{
+			name:  "truncated insert",
+			delta: []byte{12, 3, 3, 'a'},
+			err:   true,
+		},
+		{
+			name: "huge result size",
+			// The result size is 1<<63, as a varint.
+			delta: []byte{12, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01, 3, 'a', 'b', 'c'},
+			err:   true,
+		},
+		{
+			name:  "more content than the result size",
+			delta: []byte{12, 2, 3, 'a', 'b', 'c'},
+			err:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyDelta(base, tt.delta)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("applyDelta() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("applyDelta() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+// Package git reads local Git repositories, without depending on the `git`
+// command. Only what's needed for listing files is supported: the status of
+// the files, the ignored files, the history and the references.
+package git
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrNotRepository is returned when the path isn't inside a repository.
+	ErrNotRepository = errors.New("not a git repository")
+	// ErrUnbornBranch is returned when the current branch has no commits.
+	ErrUnbornBranch = errors.New("the current branch has no commits")
+)
+
+// Repository represents a local Git repository.
+type Repository struct {
+	// WorkTree is the absolute path of the working tree.
+	WorkTree string
+	// gitDir is the directory with the HEAD and the index of the working
+	// tree, and commonDir is the one with the objects and the references.
+	// They're different in linked working trees.
+	gitDir     string
+	commonDir  string
+	packs      []*pack
+	packsMutex sync.Mutex
+}
+
+// Open opens the repository containing the path, looking for a `.git`
+// directory in it and in its parents.
+func Open(path string) (*Repository, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		dot := filepath.Join(path, ".git")
+		if info, err := os.Stat(dot); err == nil {
+			if info.IsDir() {
+				return newRepository(path, dot)
+			}
+			// In submodules and linked working trees, `.git` is a file
+			// pointing to the real directory.
+			gitDir, err := readGitFile(dot)
+			if err != nil {
+				return nil, err
+			}
+			return newRepository(path, gitDir)
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return nil, ErrNotRepository
+		}
+		path = parent
+	}
+}
+
+func newRepository(workTree, gitDir string) (*Repository, error) {
+	if _, err := os.Stat(filepath.Join(gitDir, "HEAD")); err != nil {
+		return nil, ErrNotRepository
+	}
+	r := &Repository{WorkTree: workTree, gitDir: gitDir, commonDir: gitDir}
+	if b, err := ioutil.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		common := strings.TrimSpace(string(b))
+		if !filepath.IsAbs(common) {
+			common = filepath.Join(gitDir, common)
+		}
+		r.commonDir = common
+	}
+	return r, nil
+}
+
+func readGitFile(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	line := strings.TrimSpace(string(b))
+	if !strings.HasPrefix(line, "gitdir: ") {
+		return "", ErrNotRepository
+	}
+	gitDir := strings.TrimPrefix(line, "gitdir: ")
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(path), gitDir)
+	}
+	return gitDir, nil
+}
+
+// Rel returns the path relative to the working tree, with slashes, as Git
+// stores it. It returns false if the path isn't inside the working tree.
+func (r *Repository) Rel(path string) (string, bool) {
+	rel, err := filepath.Rel(r.WorkTree, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	if rel == "." {
+		return "", true
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// Head returns the reference HEAD points to, like "refs/heads/master", and
+// the commit it resolves to. The reference is empty when HEAD is detached.
+// If the branch has no commits, ErrUnbornBranch is returned along with it.
+func (r *Repository) Head() (string, Hash, error) {
+	b, err := ioutil.ReadFile(filepath.Join(r.gitDir, "HEAD"))
+	if err != nil {
+		return "", Hash{}, err
+	}
+	line := strings.TrimSpace(string(b))
+	if !strings.HasPrefix(line, "ref: ") {
+		h, err := ParseHash(line)
+		return "", h, err
+	}
+	ref := strings.TrimPrefix(line, "ref: ")
+	h, err := r.Resolve(ref)
+	if err == errRefNotFound {
+		err = ErrUnbornBranch
+	}
+	return ref, h, err
+}
+
+var errRefNotFound = errors.New("reference not found")
+
+// Resolve returns the commit the reference points to, following symbolic
+// references.
+func (r *Repository) Resolve(ref string) (Hash, error) {
+	for i := 0; i < 10; i++ {
+		b, err := ioutil.ReadFile(filepath.Join(r.commonDir, filepath.FromSlash(ref)))
+		if os.IsNotExist(err) {
+			return r.packedRef(ref)
+		}
+		if err != nil {
+			return Hash{}, err
+		}
+		line := strings.TrimSpace(string(b))
+		if !strings.HasPrefix(line, "ref: ") {
+			return ParseHash(line)
+		}
+		ref = strings.TrimPrefix(line, "ref: ")
+	}
+	return Hash{}, errors.New("too many levels of symbolic references")
+}
+
+// packedRef looks for the reference in the `packed-refs` file.
+func (r *Repository) packedRef(ref string) (Hash, error) {
+	file, err := os.Open(filepath.Join(r.commonDir, "packed-refs"))
+	if os.IsNotExist(err) {
+		return Hash{}, errRefNotFound
+	}
+	if err != nil {
+		return Hash{}, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "^") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == ref {
+			return ParseHash(fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Hash{}, err
+	}
+	return Hash{}, errRefNotFound
+}
@@ -0,0 +1,171 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	f := newFixture(t)
+	f.commit("first", "a/b/c", "c\n")
+	f.git("worktree", "add", "-q", "linked")
+
+	tests := []struct {
+		name     string
+		path     string
+		workTree string
+	}{
+		{"root", f.dir, f.dir},
+		{"subdirectory", f.path("a/b"), f.dir},
+		{"file", f.path("a/b/c"), f.dir},
+		{"linked working tree", f.path("linked/a"), f.path("linked")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Open(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.WorkTree != tt.workTree {
+				t.Errorf("WorkTree = %q, want %q", r.WorkTree, tt.workTree)
+			}
+		})
+	}
+
+	t.Run("linked working tree shares the objects", func(t *testing.T) {
+		r, err := Open(f.path("linked"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.gitDir == r.commonDir {
+			t.Errorf("gitDir and commonDir are both %q", r.gitDir)
+		}
+		if _, _, err := r.Head(); err != nil {
+			t.Errorf("Head() error = %v", err)
+		}
+	})
+
+	t.Run("not a repository", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "ipe-git")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		if _, err := Open(dir); err != ErrNotRepository {
+			t.Errorf("Open() error = %v, want %v", err, ErrNotRepository)
+		}
+	})
+}
+
+func TestRel(t *testing.T) {
+	r := &Repository{WorkTree: filepath.FromSlash("/repo")}
+	tests := []struct {
+		path string
+		rel  string
+		ok   bool
+	}{
+		{"/repo", "", true},
+		{"/repo/a", "a", true},
+		{"/repo/a/b", "a/b", true},
+		{"/repository", "", false},
+		{"/", "", false},
+	}
+	for _, tt := range tests {
+		rel, ok := r.Rel(filepath.FromSlash(tt.path))
+		if rel != tt.rel || ok != tt.ok {
+			t.Errorf("Rel(%q) = %q, %v, want %q, %v", tt.path, rel, ok, tt.rel, tt.ok)
+		}
+	}
+}
+
+func TestHead(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(f *fixture)
+		ref   string
+		err   error
+	}{
+		{
+			name:  "unborn branch",
+			setup: func(f *fixture) {},
+			ref:   "refs/heads/master",
+			err:   ErrUnbornBranch,
+		},
+		{
+			name:  "branch",
+			setup: func(f *fixture) { f.commit("first", "a", "a\n") },
+			ref:   "refs/heads/master",
+		},
+		{
+			name: "other branch",
+			setup: func(f *fixture) {
+				f.commit("first", "a", "a\n")
+				f.git("checkout", "-q", "-b", "feature/x")
+			},
+			ref: "refs/heads/feature/x",
+		},
+		{
+			name: "packed references",
+			setup: func(f *fixture) {
+				f.commit("first", "a", "a\n")
+				f.git("pack-refs", "--all")
+			},
+			ref: "refs/heads/master",
+		},
+		{
+			name: "detached HEAD",
+			setup: func(f *fixture) {
+				f.commit("first", "a", "a\n")
+				f.commit("second", "a", "b\n")
+				f.git("checkout", "-q", "--detach", "HEAD~1")
+			},
+			ref: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			tt.setup(f)
+			ref, h, err := f.open().Head()
+			if err != tt.err {
+				t.Fatalf("Head() error = %v, want %v", err, tt.err)
+			}
+			if ref != tt.ref {
+				t.Errorf("Head() ref = %q, want %q", ref, tt.ref)
+			}
+			if tt.err != nil {
+				return
+			}
+			if want := f.git("rev-parse", "HEAD"); h.String() != want {
+				t.Errorf("Head() hash = %s, want %s", h, want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	f := newFixture(t)
+	f.commit("first", "a", "a\n")
+	f.git("tag", "loose")
+	f.git("branch", "packed")
+	f.git("pack-refs", "--all")
+	f.git("symbolic-ref", "refs/heads/link", "refs/heads/packed")
+	want := f.git("rev-parse", "HEAD")
+	r := f.open()
+
+	for _, ref := range []string{"refs/heads/master", "refs/tags/loose", "refs/heads/packed", "refs/heads/link"} {
+		h, err := r.Resolve(ref)
+		if err != nil {
+			t.Errorf("Resolve(%q) error = %v", ref, err)
+			continue
+		}
+		if h.String() != want {
+			t.Errorf("Resolve(%q) = %s, want %s", ref, h, want)
+		}
+	}
+	if _, err := r.Resolve("refs/heads/missing"); err != errRefNotFound {
+		t.Errorf("Resolve(missing) error = %v, want %v", err, errRefNotFound)
+	}
+}
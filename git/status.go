@@ -0,0 +1,297 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// StatusCode represents a letter of the short format of `git status`.
+type StatusCode byte
+
+const (
+	// Unmodified means the file has no changes.
+	Unmodified StatusCode = ' '
+	// Modified means the content or the mode of the file changed.
+	Modified StatusCode = 'M'
+	// TypeChanged means the file became a link or the other way around.
+	TypeChanged StatusCode = 'T'
+	// Added means the file is new.
+	Added StatusCode = 'A'
+	// Deleted means the file was removed.
+	Deleted StatusCode = 'D'
+	// Conflicted means the file has merge conflicts.
+	Conflicted StatusCode = 'U'
+	// Untracked means the file isn't in the repository.
+	Untracked StatusCode = '?'
+	// Ignored means the file is ignored by the repository.
+	Ignored StatusCode = '!'
+)
+
+// statusPriority orders the codes by importance, to summarize the status
+// of directories.
+var statusPriority = map[StatusCode]int{
+	Unmodified:  0,
+	Ignored:     1,
+	Untracked:   2,
+	TypeChanged: 3,
+	Added:       4,
+	Deleted:     5,
+	Modified:    6,
+	Conflicted:  7,
+}
+
+// FileStatus represents the status of a file, as the two letters of the
+// short format of `git status`: the changes in the index (staged) and the
+// changes in the working tree (unstaged).
+type FileStatus struct {
+	Staged   StatusCode
+	Unstaged StatusCode
+}
+
+var (
+	clean          = FileStatus{Unmodified, Unmodified}
+	untrackedFile  = FileStatus{Untracked, Untracked}
+	conflictStages = map[int]FileStatus{
+		1: {Deleted, Deleted},
+		2: {Added, Conflicted},
+		3: {Conflicted, Deleted},
+		4: {Conflicted, Added},
+		5: {Deleted, Conflicted},
+		6: {Added, Added},
+		7: {Conflicted, Conflicted},
+	}
+)
+
+// String returns the two letters of the status.
+func (s FileStatus) String() string {
+	return string([]byte{byte(s.Staged), byte(s.Unstaged)})
+}
+
+// IsClean reports whether the file has no changes.
+func (s FileStatus) IsClean() bool { return s == clean }
+
+// IsConflicted reports whether the file has merge conflicts.
+func (s FileStatus) IsConflicted() bool {
+	return s.Staged == Conflicted || s.Unstaged == Conflicted ||
+		(s.Staged == Added && s.Unstaged == Added) ||
+		(s.Staged == Deleted && s.Unstaged == Deleted)
+}
+
+// combine returns the most important status of both, for each letter. The
+// receiver is a summary, so only the other status is checked for conflicts:
+// a summary of files deleted in the index and in the working tree isn't one.
+func (s FileStatus) combine(other FileStatus) FileStatus {
+	if other.IsConflicted() {
+		return FileStatus{Conflicted, Conflicted}
+	}
+	if statusPriority[other.Staged] > statusPriority[s.Staged] {
+		s.Staged = other.Staged
+	}
+	if statusPriority[other.Unstaged] > statusPriority[s.Unstaged] {
+		s.Unstaged = other.Unstaged
+	}
+	return s
+}
+
+// Status represents the status of the files of a repository. Untracked
+// files are found when asked for, so only the listed directories are read.
+// It isn't safe for concurrent use.
+type Status struct {
+	repo      *Repository
+	ignore    *Ignore
+	files     map[string]FileStatus
+	untracked map[string]bool
+}
+
+// Status compares the HEAD commit, the index and the working tree, like
+// `git status`. Renames aren't detected and the content filters, like the
+// conversion of line endings, aren't applied.
+func (r *Repository) Status() (*Status, error) {
+	entries, indexTime, err := r.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	head := make(map[string]treeEntry)
+	if _, h, err := r.Head(); err == nil {
+		commit, err := r.ReadCommit(h)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.treeFiles(commit.Tree, "", head); err != nil {
+			return nil, err
+		}
+	} else if err != ErrUnbornBranch {
+		return nil, err
+	}
+
+	s := &Status{
+		repo:      r,
+		ignore:    r.newIgnore(entries),
+		files:     make(map[string]FileStatus),
+		untracked: make(map[string]bool),
+	}
+	stages := make(map[string]int)
+	for _, e := range entries {
+		if e.stage > 0 {
+			stages[e.path] |= 1 << uint(e.stage-1)
+			continue
+		}
+		st := clean
+		h, inHead := head[e.path]
+		switch {
+		case e.intentToAdd:
+			st.Unstaged = Added
+		case !inHead:
+			st.Staged = Added
+		case h.mode&modeType != e.mode&modeType:
+			st.Staged = TypeChanged
+		case h.hash != e.hash || h.mode != e.mode:
+			st.Staged = Modified
+		}
+		if !e.intentToAdd && !e.skipWorktree && e.mode&modeType != modeGitlink {
+			st.Unstaged = r.worktreeStatus(e, indexTime)
+		}
+		if st != clean {
+			s.files[e.path] = st
+		}
+	}
+	for p, bits := range stages {
+		s.files[p] = conflictStages[bits]
+	}
+	for p := range head {
+		if !s.ignore.tracked[p] {
+			s.files[p] = FileStatus{Deleted, Unmodified}
+		}
+	}
+	return s, nil
+}
+
+// worktreeStatus compares the file in the working tree with its entry in
+// the index. The content is only read if the size or the modification time
+// changed, or if it was modified right after the index was written.
+func (r *Repository) worktreeStatus(e indexEntry, indexTime time.Time) StatusCode {
+	name := filepath.Join(r.WorkTree, filepath.FromSlash(e.path))
+	info, err := os.Lstat(name)
+	if err != nil {
+		return Deleted
+	}
+	mode := worktreeMode(info)
+	switch {
+	case mode == 0:
+		return Deleted
+	case mode&modeType != e.mode&modeType:
+		return TypeChanged
+	case mode != e.mode && runtime.GOOS != "windows":
+		return Modified
+	case uint32(info.Size()) == e.size && info.ModTime().Equal(e.modTime) && e.modTime.Before(indexTime):
+		return Unmodified
+	}
+	var content []byte
+	if mode == modeSymlink {
+		var target string
+		target, err = os.Readlink(name)
+		content = []byte(filepath.ToSlash(target))
+	} else {
+		content, err = ioutil.ReadFile(name)
+	}
+	if err != nil || hashBlob(content) != e.hash {
+		return Modified
+	}
+	return Unmodified
+}
+
+// worktreeMode returns the mode Git would store for the file, or zero if
+// it can't be stored.
+func worktreeMode(info os.FileInfo) uint32 {
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		return modeSymlink
+	case !info.Mode().IsRegular():
+		return 0
+	case info.Mode()&0111 != 0:
+		return modeExec
+	default:
+		return modeFile
+	}
+}
+
+// File returns the status of the file, by its path.
+func (s *Status) File(name string) FileStatus {
+	rel, ok := s.repo.Rel(name)
+	if !ok || rel == "" || isGitDir(rel) {
+		return clean
+	}
+	if st, ok := s.files[rel]; ok {
+		return st
+	}
+	if s.ignore.tracked[rel] {
+		return clean
+	}
+	if s.ignore.matchRel(rel, false) {
+		return FileStatus{Ignored, Ignored}
+	}
+	return untrackedFile
+}
+
+// Dir returns the status of all the files in the directory, by its path,
+// summarized: each letter is the most important one among the files.
+func (s *Status) Dir(name string) FileStatus {
+	rel, ok := s.repo.Rel(name)
+	if !ok || isGitDir(rel) {
+		return clean
+	}
+	if s.ignore.matchRel(rel, true) {
+		return FileStatus{Ignored, Ignored}
+	}
+	st := clean
+	for p, fileStatus := range s.files {
+		if isDirPrefix(rel, p) {
+			st = st.combine(fileStatus)
+		}
+	}
+	if s.hasUntracked(rel) {
+		st = st.combine(untrackedFile)
+	}
+	return st
+}
+
+// IsDirty reports whether any tracked file has changes, staged or not.
+// Untracked files don't count.
+func (s *Status) IsDirty() bool { return len(s.files) > 0 }
+
+// hasUntracked reports whether there are untracked files in the directory,
+// reading the working tree. The result is kept for the next calls.
+func (s *Status) hasUntracked(dir string) bool {
+	if found, ok := s.untracked[dir]; ok {
+		return found
+	}
+	found := false
+	infos, _ := ioutil.ReadDir(filepath.Join(s.repo.WorkTree, filepath.FromSlash(dir)))
+	for _, info := range infos {
+		if info.Name() == ".git" {
+			continue
+		}
+		child := info.Name()
+		if dir != "" {
+			child = dir + "/" + child
+		}
+		if s.ignore.tracked[child] || s.ignore.matchRel(child, info.IsDir()) {
+			continue
+		}
+		if !info.IsDir() || s.hasUntracked(child) {
+			found = true
+			break
+		}
+	}
+	s.untracked[dir] = found
+	return found
+}
+
+// isGitDir reports whether the path is the `.git` directory or is in it.
+func isGitDir(rel string) bool {
+	return rel == ".git" || strings.HasPrefix(rel, ".git/")
+}
@@ -0,0 +1,186 @@
+package git
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// changesFixture creates a repository with a change of each kind, staged
+// and not.
+func changesFixture(t *testing.T) *fixture {
+	f := newFixture(t)
+	f.commit("first",
+		".gitignore", "*.log\n",
+		"modified", "a\n",
+		"staged", "a\n",
+		"both", "a\n",
+		"dir/deleted", "a\n",
+		"dir/removed", "a\n",
+		"dir/modified", "a\n",
+		"dir/sub/clean", "a\n",
+		"typechange", "a\n",
+	)
+	f.write("modified", "b\n")
+	f.write("staged", "b\n")
+	f.write("both", "b\n")
+	f.git("add", "staged", "both")
+	f.write("both", "c\n")
+	if err := os.Remove(f.path("dir/deleted")); err != nil {
+		t.Fatal(err)
+	}
+	f.git("rm", "-q", "dir/removed")
+	f.write("dir/modified", "b\n")
+	f.write("added", "a\n")
+	f.git("add", "added")
+	f.write("intent", "a\n")
+	f.git("add", "-N", "intent")
+	if err := os.Remove(f.path("typechange")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("modified", f.path("typechange")); err != nil {
+		t.Skip("links aren't supported:", err)
+	}
+	f.write("untracked/file", "a\n")
+	f.write("dir/sub/debug.log", "a\n")
+	return f
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T) *fixture
+		// dirs are the expected summaries of the directories.
+		dirs map[string]string
+	}{
+		{
+			name:  "changes",
+			setup: changesFixture,
+			dirs: map[string]string{
+				"dir":       "DM",
+				"dir/sub":   "  ",
+				"untracked": "??",
+			},
+		},
+		{
+			name: "detached HEAD",
+			setup: func(t *testing.T) *fixture {
+				f := changesFixture(t)
+				f.git("checkout", "-q", "--detach")
+				return f
+			},
+		},
+		{
+			name: "unborn branch",
+			setup: func(t *testing.T) *fixture {
+				f := newFixture(t)
+				f.write("added", "a\n")
+				f.write("dir/added", "a\n")
+				f.git("add", "-A")
+				f.write("untracked", "a\n")
+				return f
+			},
+			dirs: map[string]string{"dir": "A "},
+		},
+		{
+			name: "conflicts",
+			setup: func(t *testing.T) *fixture {
+				f := newFixture(t)
+				f.commit("base", "dir/both", "base\n", "ours", "base\n", "theirs", "base\n")
+				f.git("checkout", "-q", "-b", "other")
+				f.commit("theirs", "dir/both", "theirs\n", "added", "theirs\n", "ours", "theirs\n")
+				f.git("rm", "-q", "theirs")
+				f.commit("removed")
+				f.git("checkout", "-q", "master")
+				f.commit("ours", "dir/both", "ours\n", "added", "ours\n", "theirs", "ours\n")
+				f.git("rm", "-q", "ours")
+				f.commit("removed")
+				if _, err := f.run("merge", "-q", "other"); err == nil {
+					t.Fatal("the merge succeeded without conflicts")
+				}
+				return f
+			},
+			dirs: map[string]string{"dir": "UU"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.setup(t)
+			want := porcelainStatus(f)
+			s, err := f.open().Status()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for path, w := range want {
+				if got := s.File(f.path(path)).String(); got != w {
+					t.Errorf("File(%q) = %q, want %q", path, got, w)
+				}
+			}
+			for dir, w := range tt.dirs {
+				if got := s.Dir(f.path(dir)).String(); got != w {
+					t.Errorf("Dir(%q) = %q, want %q", dir, got, w)
+				}
+			}
+		})
+	}
+}
+
+// porcelainStatus returns the status of every file in the working tree and
+// in the index, as told by `git status`.
+func porcelainStatus(f *fixture) map[string]string {
+	f.t.Helper()
+	status := make(map[string]string)
+	for _, path := range strings.Split(f.git("ls-files"), "\n") {
+		if path != "" {
+			status[path] = "  "
+		}
+	}
+	out := f.git("status", "--porcelain", "--ignored", "--untracked-files=all", "--no-renames")
+	for _, line := range strings.Split(out, "\n") {
+		if line != "" {
+			status[line[3:]] = line[:2]
+		}
+	}
+	return status
+}
+
+func TestStatusIsDirty(t *testing.T) {
+	f := newFixture(t)
+	f.commit("first", "a", "a\n")
+	f.write("untracked", "a\n")
+	s, err := f.open().Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.IsDirty() {
+		t.Error("IsDirty() = true with only untracked files")
+	}
+
+	f.write("a", "b\n")
+	if s, err = f.open().Status(); err != nil {
+		t.Fatal(err)
+	}
+	if !s.IsDirty() {
+		t.Error("IsDirty() = false with a modified file")
+	}
+}
+
+func TestFileStatusCombine(t *testing.T) {
+	tests := []struct {
+		a, b FileStatus
+		want string
+	}{
+		{clean, FileStatus{Modified, Unmodified}, "M "},
+		{FileStatus{Added, Unmodified}, FileStatus{Unmodified, Modified}, "AM"},
+		{FileStatus{Modified, Untracked}, FileStatus{Deleted, Ignored}, "M?"},
+		{FileStatus{Modified, Modified}, FileStatus{Added, Added}, "UU"},
+		{FileStatus{Conflicted, Conflicted}, clean, "UU"},
+		// A summary with deletions in both isn't a conflict.
+		{FileStatus{Deleted, Deleted}, FileStatus{Unmodified, Modified}, "DM"},
+	}
+	for _, tt := range tests {
+		if got := tt.a.combine(tt.b).String(); got != tt.want {
+			t.Errorf("%q.combine(%q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -1,8 +1,19 @@
 package ipefmt
 
-import "regexp"
+import (
+	"regexp"
+	"text/template"
+	"time"
+)
 
 const (
+	// ArgAlignLeft represents an option for the `align` flag.
+	// It means the column will be aligned to the left.
+	ArgAlignLeft = "left"
+	// ArgAlignRight represents an option for the `align` flag.
+	// It means the column will be aligned to the right.
+	ArgAlignRight = "right"
+
 	// ArgColorNever represents an option for the `color` flag.
 	// It means the output will never be printed with colors.
 	ArgColorNever = "never"
@@ -10,9 +21,103 @@ const (
 	// It means the output will always be printed with colors.
 	ArgColorAlways = "always"
 	// ArgColorAuto represents an option for the `color` flag.
-	// It means the output will be printed with colors, only if it is stdout.
+	// It means the output will be printed with colors, only if it is written
+	// to a terminal.
 	ArgColorAuto = "auto"
 
+	// ArgControlHide represents an option for the `control-chars` flag.
+	// It means non-printable characters in names will be shown as "?".
+	ArgControlHide = "hide"
+	// ArgControlShow represents an option for the `control-chars` flag.
+	// It means names will be written byte by byte, as they are.
+	ArgControlShow = "show"
+	// ArgControlAuto represents an option for the `control-chars` flag.
+	// It means non-printable characters will be hidden, only if the output
+	// is written to a terminal.
+	ArgControlAuto = "auto"
+
+	// ArgFormatText represents an option for the `format` flag.
+	// It means the output will be in grid, long or tree view, based on the
+	// other flags.
+	ArgFormatText = "text"
+	// ArgFormatJSON represents an option for the `format` flag.
+	// It means the output will be nested JSON objects, mirroring the tree.
+	ArgFormatJSON = "json"
+	// ArgFormatNDJSON represents an option for the `format` flag.
+	// It means the output will be one JSON object per line for each entry.
+	ArgFormatNDJSON = "ndjson"
+	// ArgFormatCSV represents an option for the `format` flag.
+	// It means the long view columns will be written as comma-separated values.
+	ArgFormatCSV = "csv"
+	// ArgFormatTSV represents an option for the `format` flag.
+	// It means the long view columns will be written as tab-separated values.
+	ArgFormatTSV = "tsv"
+	// ArgFormatXML represents an option for the `format` flag.
+	// It means the output will be XML, compatible with `tree -X`.
+	ArgFormatXML = "xml"
+	// ArgFormatHTML represents an option for the `format` flag.
+	// It means the output will be a standalone HTML page.
+	ArgFormatHTML = "html"
+	// ArgFormatMarkdown represents an option for the `format` flag.
+	// It means the output will be a Markdown table or tree.
+	ArgFormatMarkdown = "markdown"
+
+	// ArgGlobName represents an option for the `glob-match` flag.
+	// It means globs will be matched against the names of the entries.
+	ArgGlobName = "name"
+	// ArgGlobPath represents an option for the `glob-match` flag.
+	// It means globs will be matched against the paths of the entries,
+	// relative to the source.
+	ArgGlobPath = "path"
+
+	// ArgMarkdownFence represents an option for the `markdown-style` flag.
+	// It means the tree will be written in a fenced code block.
+	ArgMarkdownFence = "fence"
+	// ArgMarkdownList represents an option for the `markdown-style` flag.
+	// It means the tree will be written as a nested list.
+	ArgMarkdownList = "list"
+
+	// ArgQuotingLiteral represents an option for the `quoting-style` flag.
+	// It means names will be written as they are.
+	ArgQuotingLiteral = "literal"
+	// ArgQuotingShell represents an option for the `quoting-style` flag.
+	// It means names will be quoted for the shell, if needed.
+	ArgQuotingShell = "shell"
+	// ArgQuotingShellEscape represents an option for the `quoting-style` flag.
+	// It means names will be quoted for the shell, if needed, with the
+	// non-printable characters escaped.
+	ArgQuotingShellEscape = "shell-escape"
+	// ArgQuotingC represents an option for the `quoting-style` flag.
+	// It means names will be quoted as C strings.
+	ArgQuotingC = "c"
+	// ArgQuotingEscape represents an option for the `quoting-style` flag.
+	// It means names will be escaped as C strings, without the quotes.
+	ArgQuotingEscape = "escape"
+
+	// ArgSizeIEC represents an option for the `size-style` flag.
+	// It means sizes will be shown in powers of 1024 (KiB, MiB...).
+	ArgSizeIEC = "iec"
+	// ArgSizeSI represents an option for the `size-style` flag.
+	// It means sizes will be shown in powers of 1000 (kB, MB...).
+	ArgSizeSI = "si"
+	// ArgSizeBytes represents an option for the `size-style` flag.
+	// It means sizes will be shown as the exact number of bytes.
+	ArgSizeBytes = "bytes"
+	// ArgSizeBlocks represents an option for the `size-style` flag.
+	// It means sizes will be shown as the number of 512-byte blocks needed
+	// to store them, unlike the `blocks` column, with the allocated ones.
+	ArgSizeBlocks = "blocks"
+
+	// ArgThemeDefault represents an option for the `theme` flag.
+	// It means the long view columns will be colored like `exa` does.
+	ArgThemeDefault = "default"
+	// ArgThemeMono represents an option for the `theme` flag.
+	// It means the long view columns will only be bold, dim or underlined.
+	ArgThemeMono = "mono"
+	// ArgThemeNone represents an option for the `theme` flag.
+	// It means the long view columns will not be colored.
+	ArgThemeNone = "none"
+
 	// ArgTimeAcc represents an option for the `time` flag.
 	// It means that the "accessed time" will be printed in long view.
 	ArgTimeAcc = "accessed"
@@ -23,6 +128,25 @@ const (
 	// It means that the "created time" will be printed in long view.
 	ArgTimeCrt = "created"
 
+	// ArgTimeStyleDefault represents an option for the `time-style` flag.
+	// It means timestamps will be shown as "2 Jan 15:04" or "2 Jan 2006".
+	ArgTimeStyleDefault = "default"
+	// ArgTimeStyleISO represents an option for the `time-style` flag.
+	// It means timestamps will be shown as "01-02 15:04" or "2006-01-02".
+	ArgTimeStyleISO = "iso"
+	// ArgTimeStyleLongISO represents an option for the `time-style` flag.
+	// It means timestamps will be shown as "2006-01-02 15:04".
+	ArgTimeStyleLongISO = "long-iso"
+	// ArgTimeStyleFullISO represents an option for the `time-style` flag.
+	// It means timestamps will be shown with nanoseconds and time zone.
+	ArgTimeStyleFullISO = "full-iso"
+	// ArgTimeStyleRelative represents an option for the `time-style` flag.
+	// It means timestamps will be shown relative to now, like "3 hours ago".
+	ArgTimeStyleRelative = "relative"
+	// ArgTimeStyleUnix represents an option for the `time-style` flag.
+	// It means timestamps will be shown as seconds since the Unix epoch.
+	ArgTimeStyleUnix = "unix"
+
 	// ArgSortNone represents an option for the `sort` flag.
 	// It means the output will not be sorted.
 	ArgSortNone = "none"
@@ -56,6 +180,12 @@ const (
 	// ArgSortGroup represents an option for the `sort` flag.
 	// It means the output will be sorted by group.
 	ArgSortGroup = "group"
+	// ArgSortGit represents an option for the `sort` flag.
+	// It means the output will be sorted by Git status.
+	ArgSortGit = "git"
+	// ArgSortCommit represents an option for the `sort` flag.
+	// It means the output will be sorted by the date of the last commit.
+	ArgSortCommit = "commit"
 	// ArgSortName represents an option for the `sort` flag.
 	// It means the output will be sorted by name.
 	ArgSortName = "name"
@@ -63,28 +193,65 @@ const (
 
 // ArgsInfo represents all the arguments it is needed for formatting.
 type ArgsInfo struct {
-	Across    bool
-	All       bool
-	Blocks    bool
-	Color     string
-	Classify  bool
-	Depth     uint8
-	DirsFirst bool
-	Filter    []*regexp.Regexp
-	Follow    bool
-	Group     bool
-	Header    bool
-	Ignore    []*regexp.Regexp
-	Inode     bool
-	Links     bool
-	Long      bool
-	OneLine   bool
-	Reverse   bool
-	Recursive bool
-	Separator string
-	Sort      string
-	Sources   []string
-	Time      []string
-	Tree      bool
-	Width     int
+	Across        bool
+	Align         map[string]string
+	All           bool
+	Blocks        bool
+	Color         string
+	Columns       []string
+	ControlChars  string
+	Classify      bool
+	Depth         uint8
+	DirsFirst     bool
+	Filter        []*regexp.Regexp
+	Follow        bool
+	Format        string
+	Git           bool
+	GitCommit     bool
+	GitHeader     bool
+	GitIgnore     bool
+	Glob          []string
+	GlobFoldCase  bool
+	GlobMatch     string
+	Group         bool
+	Header        bool
+	Human         bool
+	IconSet       *IconSet
+	Icons         bool
+	Ignore        []*regexp.Regexp
+	IgnoreFiles   bool
+	IgnoreGlob    []string
+	Inode         bool
+	Links         bool
+	Location      *time.Location
+	Long          bool
+	MarkdownLinks bool
+	MarkdownStyle string
+	NameColors    *NameColors
+	Null          bool
+	OneLine       bool
+	Quoting       string
+	Reverse       bool
+	Recursive     bool
+	Separator     string
+	ShowIgnored   bool
+	SizeDecimals  uint8
+	SizeStyle     string
+	Sort          string
+	Sources       []string
+	Template      *template.Template
+	Theme         Theme
+	Thousands     string
+	Time          []string
+	TimeStyle     string
+	Tree          bool
+	Width         int
+}
+
+func (args ArgsInfo) timeFormat() TimeFormat {
+	return TimeFormat{args.TimeStyle, args.Location}
+}
+
+func (args ArgsInfo) sizeFormat() SizeFormat {
+	return SizeFormat{args.SizeStyle, args.SizeDecimals, args.Thousands}
 }
@@ -0,0 +1,261 @@
+package ipefmt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Nhanderu/ipe"
+	"github.com/fatih/color"
+)
+
+// defaultLSColors are the colors of the file types used when none are
+// given, the same ones `dircolors` has built in.
+const defaultLSColors = "rs=0:di=01;34:ln=01;36:mh=00:pi=40;33:so=01;35:do=01;35:" +
+	"bd=40;33;01:cd=40;33;01:or=40;31;01:mi=00:su=37;41:sg=30;43:ca=00:" +
+	"tw=30;42:ow=34;42:st=37;44:ex=01;32"
+
+// dircolorsKeywords maps the keywords of the `dircolors` database to the
+// keys of `LS_COLORS`.
+var dircolorsKeywords = map[string]string{
+	"RESET":                 "rs",
+	"NORMAL":                "no",
+	"FILE":                  "fi",
+	"DIR":                   "di",
+	"LINK":                  "ln",
+	"SYMLINK":               "ln",
+	"MULTIHARDLINK":         "mh",
+	"FIFO":                  "pi",
+	"PIPE":                  "pi",
+	"SOCK":                  "so",
+	"DOOR":                  "do",
+	"BLK":                   "bd",
+	"BLOCK":                 "bd",
+	"CHR":                   "cd",
+	"CHAR":                  "cd",
+	"ORPHAN":                "or",
+	"MISSING":               "mi",
+	"SETUID":                "su",
+	"SETGID":                "sg",
+	"CAPABILITY":            "ca",
+	"STICKY_OTHER_WRITABLE": "tw",
+	"OTHER_WRITABLE":        "ow",
+	"STICKY":                "st",
+	"EXEC":                  "ex",
+}
+
+// fallbackClasses are the classes used when a class has no color.
+var fallbackClasses = map[string]string{
+	"or": "ln",
+	"tw": "di",
+	"ow": "di",
+	"st": "di",
+	"su": "fi",
+	"sg": "fi",
+	"ex": "fi",
+	"pi": "fi",
+	"so": "fi",
+	"bd": "fi",
+	"cd": "fi",
+	"ln": "fi",
+	"di": "fi",
+}
+
+// NameColors represents the colors of the names, by file type and by
+// suffix, as defined by `LS_COLORS`. The colors are SGR parameters, like
+// "01;34".
+type NameColors struct {
+	types    map[string]string
+	suffixes []suffixColor
+}
+
+type suffixColor struct {
+	suffix string
+	color  string
+}
+
+// DefaultNameColors returns the colors `dircolors` has built in.
+func DefaultNameColors() *NameColors {
+	return ParseLSColors(defaultLSColors)
+}
+
+// ParseLSColors parses the colors in the format of the `LS_COLORS`
+// environment variable. Invalid entries are ignored.
+func ParseLSColors(s string) *NameColors {
+	c := &NameColors{types: make(map[string]string)}
+	for _, entry := range strings.Split(s, ":") {
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		c.set(kv[0], kv[1])
+	}
+	return c
+}
+
+// ParseDircolors parses the colors in the format of the `dircolors`
+// database, like the output of `dircolors --print-database`.
+// The terminal-specific sections are ignored.
+func ParseDircolors(r io.Reader) (*NameColors, error) {
+	c := &NameColors{types: make(map[string]string)}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		key := fields[0]
+		switch {
+		case strings.HasPrefix(key, "."):
+			key = "*" + key
+		case strings.HasPrefix(key, "*"):
+		default:
+			key = dircolorsKeywords[strings.ToUpper(key)]
+		}
+		if key != "" {
+			c.set(key, fields[1])
+		}
+	}
+	return c, scanner.Err()
+}
+
+func (c *NameColors) set(key, value string) {
+	if strings.HasPrefix(key, "*") {
+		c.suffixes = append(c.suffixes, suffixColor{strings.ToLower(key[1:]), value})
+	} else {
+		c.types[key] = value
+	}
+}
+
+// lookup returns the SGR parameters of the file's color, or an empty
+// string if it has none. Suffixes only apply to regular files, like `ls`
+// does, and later entries override the previous ones. With "ln=target",
+// links are colored as their targets, by their names.
+func (c *NameColors) lookup(file ipe.File) string {
+	class := fileClass(file)
+	if class == "ln" && c.types["ln"] == "target" {
+		if target, err := file.FollowLink(); err == nil {
+			file, class = target, fileClass(target)
+		}
+	}
+	if class == "fi" {
+		name := strings.ToLower(file.Name())
+		for i := len(c.suffixes) - 1; i >= 0; i-- {
+			if strings.HasSuffix(name, c.suffixes[i].suffix) {
+				return c.suffixes[i].color
+			}
+		}
+	}
+	for class != "" {
+		if sgr, ok := c.types[class]; ok {
+			return sgr
+		}
+		class = fallbackClasses[class]
+	}
+	return ""
+}
+
+// newSGRColor returns the color of the SGR parameters, enabled or not
+// regardless of the global settings of the color package.
+func newSGRColor(sgr string, enabled bool) *color.Color {
+	var attrs []color.Attribute
+	for _, p := range strings.Split(sgr, ";") {
+		if n, err := strconv.Atoi(p); err == nil {
+			attrs = append(attrs, color.Attribute(n))
+		}
+	}
+	c := color.New(attrs...)
+	if enabled {
+		c.EnableColor()
+	} else {
+		c.DisableColor()
+	}
+	return c
+}
+
+// colorName colors the name of the file by its type or suffix. The files
+// ignored by Git are dimmed instead, when they're shown.
+func (f commonFormatter) colorName(file ipe.File, name string) string {
+	if f.args.ShowIgnored && f.repos.gitIgnored(file) {
+		return f.painter.paint("ignored", name)
+	}
+	sgr := f.colors.lookup(file)
+	if !f.color || sgr == "" || sgr == "0" || sgr == "00" {
+		return name
+	}
+	return newSGRColor(sgr, true).Sprint(name)
+}
+
+// ansiPalette are the 16 basic terminal colors, as CSS colors.
+var ansiPalette = []string{
+	"#000000", "#cc0000", "#4e9a06", "#c4a000", "#3465a4", "#75507b", "#06989a", "#d3d7cf",
+	"#555753", "#ef2929", "#8ae234", "#fce94f", "#729fcf", "#ad7fa8", "#34e2e2", "#eeeeec",
+}
+
+// sgrToCSS converts the SGR parameters to CSS declarations.
+func sgrToCSS(sgr string) string {
+	var params []int
+	for _, p := range strings.Split(sgr, ";") {
+		n, _ := strconv.Atoi(p)
+		params = append(params, n)
+	}
+	var css []string
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		switch {
+		case p == 1:
+			css = append(css, "font-weight: bold")
+		case p == 2:
+			css = append(css, "opacity: .6")
+		case p == 3:
+			css = append(css, "font-style: italic")
+		case p == 4:
+			css = append(css, "text-decoration: underline")
+		case p >= 30 && p <= 37:
+			css = append(css, "color: "+ansiPalette[p-30])
+		case p >= 90 && p <= 97:
+			css = append(css, "color: "+ansiPalette[p-90+8])
+		case p >= 40 && p <= 47:
+			css = append(css, "background: "+ansiPalette[p-40])
+		case p >= 100 && p <= 107:
+			css = append(css, "background: "+ansiPalette[p-100+8])
+		case (p == 38 || p == 48) && i+2 < len(params) && params[i+1] == 5:
+			css = append(css, cssProperty(p)+xterm256(params[i+2]))
+			i += 2
+		case (p == 38 || p == 48) && i+4 < len(params) && params[i+1] == 2:
+			css = append(css, fmt.Sprintf("%s#%02x%02x%02x", cssProperty(p), params[i+2], params[i+3], params[i+4]))
+			i += 4
+		}
+	}
+	return strings.Join(css, "; ")
+}
+
+func cssProperty(sgr int) string {
+	if sgr == 48 {
+		return "background: "
+	}
+	return "color: "
+}
+
+// xterm256 returns the CSS color of the 256-color palette.
+func xterm256(n int) string {
+	switch {
+	case n < 0 || n > 255:
+		return "inherit"
+	case n < 16:
+		return ansiPalette[n]
+	case n < 232:
+		levels := []int{0, 95, 135, 175, 215, 255}
+		n -= 16
+		return fmt.Sprintf("#%02x%02x%02x", levels[n/36], levels[n/6%6], levels[n%6])
+	default:
+		gray := 8 + (n-232)*10
+		return fmt.Sprintf("#%02x%02x%02x", gray, gray, gray)
+	}
+}
@@ -0,0 +1,83 @@
+package ipefmt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNameColorsLookup(t *testing.T) {
+	dir := newTestDir(t, map[string]string{
+		"notes.txt":      "notes",
+		"ARCHIVE.TAR.GZ": "archive",
+		"run.sh*":        "#!/bin/sh",
+		"sub/":           "",
+		"link":           "->notes.txt",
+		"linkdir":        "->sub",
+		"broken":         "->missing",
+	})
+	tests := []struct {
+		colors string
+		name   string
+		want   string
+	}{
+		{defaultLSColors, "notes.txt", ""},
+		{defaultLSColors, "sub", "01;34"},
+		{defaultLSColors, "run.sh", "01;32"},
+		{defaultLSColors, "link", "01;36"},
+		{defaultLSColors, "broken", "40;31;01"},
+		{"*.tar.gz=01;31", "ARCHIVE.TAR.GZ", "01;31"},
+		{"*.gz=31:*.tar.gz=32", "ARCHIVE.TAR.GZ", "32"},
+		{"*.tar.gz=32:*.gz=31", "ARCHIVE.TAR.GZ", "31"},
+		{"*.sh=33:ex=01;32", "run.sh", "01;32"},
+		{"fi=37", "notes.txt", "37"},
+		{"fi=37", "run.sh", "37"},
+		{"fi=37", "sub", "37"},
+		{"ln=01;36", "broken", "01;36"},
+		{"ln=target:di=01;34", "linkdir", "01;34"},
+		{"ln=target:*.txt=35", "link", "35"},
+		{"ln=target:or=31", "broken", "31"},
+		{"bogus:di=34:=:*", "sub", "34"},
+		{"", "sub", ""},
+	}
+	for _, tt := range tests {
+		file := readTestFile(t, dir, tt.name)
+		if got := ParseLSColors(tt.colors).lookup(file); got != tt.want {
+			t.Errorf("ParseLSColors(%q).lookup(%s) = %q, want %q", tt.colors, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseDircolors(t *testing.T) {
+	dir := newTestDir(t, map[string]string{
+		"notes.txt": "notes",
+		"README.MD": "readme",
+		"run.sh*":   "#!/bin/sh",
+		"sub/":      "",
+	})
+	database := `# Configuration file for dircolors.
+TERM xterm
+DIR 01;34 # directories
+exec 01;32
+.txt 35
+*.md 36
+COLOR tty
+`
+	colors, err := ParseDircolors(strings.NewReader(database))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"notes.txt", "35"},
+		{"README.MD", "36"},
+		{"run.sh", "01;32"},
+		{"sub", "01;34"},
+	}
+	for _, tt := range tests {
+		if got := colors.lookup(readTestFile(t, dir, tt.name)); got != tt.want {
+			t.Errorf("lookup(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package ipefmt
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Nhanderu/ipe"
+)
+
+var (
+	// ErrColumnExists is returned when registering a column with the same
+	// header as an already registered one.
+	ErrColumnExists = errors.New("there is already a column with this header")
+	// ErrColumnHeader is returned when registering a column without header.
+	ErrColumnHeader = errors.New("the column has no header")
+)
+
+// Column represents a custom column of the long view.
+type Column interface {
+	// Header returns the name of the column. It's shown in the header and
+	// used to select, align and sort by the column.
+	Header() string
+	// Value returns the content of the column for the file.
+	Value(file ipe.File) string
+	// AlignRight reports whether the column is aligned to the right by
+	// default.
+	AlignRight() bool
+	// Less reports whether the file `a` should be sorted before `b`.
+	Less(a, b ipe.File) bool
+}
+
+// column represents a column of the long view.
+// The raw value function is used by the machine-readable formats. If it's
+// nil, the value function is used instead. The paint function colors the
+// value in the terminal; if it's nil, the value isn't colored. If the less
+// function is nil, the raw values are compared. The columns showing what
+// the repositories say about the files only have the functions after being
+// bound to the repositories of an output.
+type column struct {
+	name     string
+	align    alignment
+	unixOnly bool
+	value    func(file ipe.File, args ArgsInfo) string
+	raw      func(file ipe.File, args ArgsInfo) string
+	paint    func(file ipe.File, value string, p painter) string
+	less     func(a, b ipe.File) bool
+	forRepos func(repos *gitRepos, args ArgsInfo) column
+}
+
+var (
+	// longColumns is the registry of all the columns the long view can show.
+	// The name column has no value function, because its content depends on
+	// the formatter.
+	longColumns = []column{
+		{
+			name:     ArgSortInode,
+			align:    alignRight,
+			unixOnly: true,
+			value: func(file ipe.File, args ArgsInfo) string {
+				return strconv.FormatUint(file.Inode(), 10)
+			},
+			paint: func(file ipe.File, value string, p painter) string {
+				return p.paint("inode", value)
+			},
+			less: func(a, b ipe.File) bool { return a.Inode() < b.Inode() },
+		},
+		{
+			name:  ArgSortMode,
+			align: alignLeft,
+			value: func(file ipe.File, args ArgsInfo) string {
+				return file.Mode().String()
+			},
+			paint: func(file ipe.File, value string, p painter) string {
+				return p.paintMode(value)
+			},
+			less: func(a, b ipe.File) bool {
+				r := strings.NewReplacer("-", "")
+				return r.Replace(a.Mode().String()) < r.Replace(b.Mode().String())
+			},
+		},
+		{
+			name:  ArgSortSize,
+			align: alignRight,
+			value: fmtSize,
+			raw: func(file ipe.File, args ArgsInfo) string {
+				return strconv.FormatInt(file.Size(), 10)
+			},
+			paint: func(file ipe.File, value string, p painter) string {
+				return p.paintSize(file, value)
+			},
+			less: func(a, b ipe.File) bool { return a.Size() < b.Size() },
+		},
+		{
+			name:     ArgSortLinks,
+			align:    alignRight,
+			unixOnly: true,
+			value: func(file ipe.File, args ArgsInfo) string {
+				return strconv.FormatUint(file.Links(), 10)
+			},
+			paint: func(file ipe.File, value string, p painter) string {
+				return p.paint("links", value)
+			},
+			less: func(a, b ipe.File) bool { return a.Links() < b.Links() },
+		},
+		{
+			name:     ArgSortBlocks,
+			align:    alignRight,
+			unixOnly: true,
+			value:    fmtBlocks,
+			raw: func(file ipe.File, args ArgsInfo) string {
+				return strconv.FormatInt(file.Blocks(), 10)
+			},
+			paint: func(file ipe.File, value string, p painter) string {
+				return p.paint("blocks", value)
+			},
+			less: func(a, b ipe.File) bool { return a.Blocks() < b.Blocks() },
+		},
+		{
+			name:  ArgSortAccessed,
+			align: alignLeft,
+			value: func(file ipe.File, args ArgsInfo) string {
+				return fmtTime(file.AccTime(), args)
+			},
+			raw: func(file ipe.File, args ArgsInfo) string {
+				return fmtRawTime(file.AccTime(), args)
+			},
+			paint: func(file ipe.File, value string, p painter) string {
+				return p.paintTime(file.AccTime(), value)
+			},
+			less: func(a, b ipe.File) bool { return a.AccTime().Unix() < b.AccTime().Unix() },
+		},
+		{
+			name:  ArgSortModified,
+			align: alignLeft,
+			value: func(file ipe.File, args ArgsInfo) string {
+				return fmtTime(file.ModTime(), args)
+			},
+			raw: func(file ipe.File, args ArgsInfo) string {
+				return fmtRawTime(file.ModTime(), args)
+			},
+			paint: func(file ipe.File, value string, p painter) string {
+				return p.paintTime(file.ModTime(), value)
+			},
+			less: func(a, b ipe.File) bool { return a.ModTime().Unix() < b.ModTime().Unix() },
+		},
+		{
+			name:  ArgSortCreated,
+			align: alignLeft,
+			value: func(file ipe.File, args ArgsInfo) string {
+				return fmtTime(file.CrtTime(), args)
+			},
+			raw: func(file ipe.File, args ArgsInfo) string {
+				return fmtRawTime(file.CrtTime(), args)
+			},
+			paint: func(file ipe.File, value string, p painter) string {
+				return p.paintTime(file.CrtTime(), value)
+			},
+			less: func(a, b ipe.File) bool { return a.CrtTime().Unix() < b.CrtTime().Unix() },
+		},
+		{
+			name:     ArgSortUser,
+			align:    alignLeft,
+			unixOnly: true,
+			value: func(file ipe.File, args ArgsInfo) string {
+				return file.User().Username
+			},
+			paint: func(file ipe.File, value string, p painter) string {
+				return p.paintUser(file, value)
+			},
+			less: func(a, b ipe.File) bool { return a.User().Uid < b.User().Uid },
+		},
+		{
+			name:     ArgSortGroup,
+			align:    alignLeft,
+			unixOnly: true,
+			value: func(file ipe.File, args ArgsInfo) string {
+				return file.Group().Name
+			},
+			paint: func(file ipe.File, value string, p painter) string {
+				return p.paintGroup(file, value)
+			},
+			less: func(a, b ipe.File) bool { return a.Group().Gid < b.Group().Gid },
+		},
+		{
+			name:     ArgSortGit,
+			align:    alignLeft,
+			forRepos: gitStatusColumn,
+		},
+		{
+			name:     ArgSortCommit,
+			align:    alignLeft,
+			forRepos: gitCommitColumn,
+		},
+		{
+			name:  ArgSortName,
+			align: alignLeft,
+			less:  func(a, b ipe.File) bool { return a.Name() < b.Name() },
+		},
+	}
+	longColumnsMutex sync.RWMutex
+)
+
+// RegisterColumn registers a custom column, making it available to the
+// long view. Custom columns are only shown when selected in `Columns`.
+func RegisterColumn(col Column) error {
+	name := col.Header()
+	if name == "" {
+		return ErrColumnHeader
+	}
+	align := alignLeft
+	if col.AlignRight() {
+		align = alignRight
+	}
+	longColumnsMutex.Lock()
+	defer longColumnsMutex.Unlock()
+	if _, ok := lookupColumn(name); ok {
+		return ErrColumnExists
+	}
+	longColumns = append(longColumns, column{
+		name:  name,
+		align: align,
+		value: func(file ipe.File, args ArgsInfo) string { return col.Value(file) },
+		less:  col.Less,
+	})
+	return nil
+}
+
+// Columns returns the names of all the available long view columns, in the
+// order they're shown, custom columns last.
+func Columns() []string {
+	longColumnsMutex.RLock()
+	defer longColumnsMutex.RUnlock()
+	names := make([]string, len(longColumns))
+	for i, col := range longColumns {
+		names[i] = col.name
+	}
+	return names
+}
+
+// ParseColumns parses a comma-separated list of long view columns,
+// validating each one of them.
+func ParseColumns(spec string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := findColumn(name); !ok {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// ParseAlign parses a comma-separated list of long view column alignments,
+// in the format "column=alignment", validating each one of them.
+func ParseAlign(spec string) (map[string]string, error) {
+	aligns := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid alignment %q, expected \"column=alignment\"", pair)
+		}
+		name, align := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		if _, ok := findColumn(name); !ok {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		if align != ArgAlignLeft && align != ArgAlignRight {
+			return nil, fmt.Errorf("unknown alignment %q", align)
+		}
+		aligns[name] = align
+	}
+	return aligns, nil
+}
+
+// rawValue returns the machine-readable content of the column for the file.
+func (col column) rawValue(file ipe.File, args ArgsInfo) string {
+	if col.raw != nil {
+		return col.raw(file, args)
+	}
+	return col.value(file, args)
+}
+
+// bind returns the column with the functions for the repositories and the
+// arguments of an output.
+func (col column) bind(repos *gitRepos, args ArgsInfo) column {
+	if col.forRepos == nil {
+		return col
+	}
+	bound := col.forRepos(repos, args)
+	bound.name, bound.align, bound.unixOnly = col.name, col.align, col.unixOnly
+	return bound
+}
+
+func findColumn(name string) (column, bool) {
+	longColumnsMutex.RLock()
+	defer longColumnsMutex.RUnlock()
+	return lookupColumn(name)
+}
+
+// lookupColumn is like findColumn, but the caller must hold the lock.
+func lookupColumn(name string) (column, bool) {
+	for _, col := range longColumns {
+		if col.name == name {
+			return col, true
+		}
+	}
+	return column{}, false
+}
+
+// columnAlign returns the alignment of the column, based on the arguments.
+// By default, numbers are aligned to the right and text to the left.
+func columnAlign(col column, args ArgsInfo) alignment {
+	switch args.Align[col.name] {
+	case ArgAlignLeft:
+		return alignLeft
+	case ArgAlignRight:
+		return alignRight
+	default:
+		return col.align
+	}
+}
+
+// columnsToShow returns the columns of the long view, in order.
+// If no columns were specified, they're chosen based on the flags.
+func columnsToShow(args ArgsInfo, repos *gitRepos) []column {
+	names := args.Columns
+	if len(names) == 0 {
+		acc, mod, crt := timesToShow(args)
+		show := map[string]bool{
+			ArgSortInode:    args.Inode,
+			ArgSortMode:     true,
+			ArgSortSize:     true,
+			ArgSortLinks:    args.Links,
+			ArgSortBlocks:   args.Blocks,
+			ArgSortAccessed: acc,
+			ArgSortModified: mod,
+			ArgSortCreated:  crt,
+			ArgSortUser:     true,
+			ArgSortGroup:    args.Group,
+			ArgSortGit:      args.Git,
+			ArgSortCommit:   args.GitCommit,
+			ArgSortName:     true,
+		}
+		longColumnsMutex.RLock()
+		for _, col := range longColumns {
+			if show[col.name] {
+				names = append(names, col.name)
+			}
+		}
+		longColumnsMutex.RUnlock()
+	}
+	var cols []column
+	for _, name := range names {
+		col, ok := findColumn(name)
+		if !ok || (col.unixOnly && osWindows) {
+			continue
+		}
+		cols = append(cols, col.bind(repos, args))
+	}
+	return cols
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/Nhanderu/gridt"
 	"github.com/Nhanderu/ipe"
@@ -15,21 +16,68 @@ type Formatter interface {
 
 	getDir(file ipe.File, grid **gridt.Grid, corners []bool)
 	getFile(file ipe.File, grid *gridt.Grid, corners []bool)
+	leaveDir(file ipe.File, corners []bool)
 	appendSource(src srcInfo)
 }
 
-// srcInfo represents the common infomation for an output node.
+// srcInfo represents the common infomation for an output node. Nested
+// nodes are the directories under a source, listed when recursing.
 type srcInfo struct {
-	file ipe.File
-	err  error
-	grid *gridt.Grid
+	file   ipe.File
+	err    error
+	grid   *gridt.Grid
+	nested bool
 }
 
+// alignment represents how the cells of a column are aligned.
+type alignment int
+
+const (
+	alignLeft alignment = iota
+	alignRight
+)
+
 // commonFormatter represents the common infomation and methods for the formatters.
 type commonFormatter struct {
-	args ArgsInfo
-	srcs []srcInfo
-	cols int
+	args        ArgsInfo
+	srcs        []srcInfo
+	cols        int
+	aligns      []alignment
+	hideControl bool
+	color       bool
+	colors      *NameColors
+	icons       *IconSet
+	painter     painter
+	repos       *gitRepos
+}
+
+// newCommonFormatter returns the common formatter. The automatic options
+// must already be decided for the writer.
+func newCommonFormatter(args ArgsInfo, cols int, repos *gitRepos) *commonFormatter {
+	hideControl := args.ControlChars == ArgControlHide
+	color := args.Color != ArgColorNever
+	colors := args.NameColors
+	if colors == nil {
+		colors = DefaultNameColors()
+	}
+	var icons *IconSet
+	if args.Icons {
+		icons = args.IconSet
+		if icons == nil {
+			icons = DefaultIconSet()
+		}
+	}
+	return &commonFormatter{
+		args:        args,
+		srcs:        make([]srcInfo, 0),
+		cols:        cols,
+		hideControl: hideControl,
+		color:       color,
+		colors:      colors,
+		icons:       icons,
+		painter:     newPainter(args),
+		repos:       repos,
+	}
 }
 
 // String outputs the formatter into a correct string.
@@ -42,16 +90,20 @@ func (f commonFormatter) String() string {
 // WriteTo writes the values of the formatter into a writer.
 func (f commonFormatter) WriteTo(w io.Writer) (int64, error) {
 	writeNames := len(f.srcs) > 1
+	eol := "\n"
+	if f.args.Null {
+		eol = "\x00"
+	}
 	var total int
 	var err error
 	for _, src := range f.srcs {
 		var n int
 		if writeNames {
-			n, err = w.Write([]byte(src.file.FullName()))
+			n, err = w.Write([]byte(f.safe(src.file.FullName())))
 			if total += n; err != nil {
 				break
 			}
-			n, err = w.Write([]byte("\n"))
+			n, err = w.Write([]byte(eol))
 			if total += n; err != nil {
 				break
 			}
@@ -66,32 +118,31 @@ func (f commonFormatter) WriteTo(w io.Writer) (int64, error) {
 				break
 			}
 		} else {
-			var d gridt.Dimensions
-			var ok bool
-			if f.cols > 0 {
-				d, ok = src.grid.FitIntoColumns(f.cols)
-			} else {
-				d, ok = src.grid.FitIntoWidth(f.args.Width)
-			}
-			if !ok || f.args.OneLine {
-				for _, cell := range src.grid.Cells() {
-					n, err = w.Write([]byte(cell))
-					if total += n; err != nil {
-						break
-					}
-					n, err = w.Write([]byte("\n"))
-					if total += n; err != nil {
-						break
-					}
+			if header, ok := f.repos.gitHeader(src.file); ok && !src.nested {
+				n, err = w.Write([]byte(f.safe(header) + eol))
+				if total += n; err != nil {
+					break
 				}
+			}
+			var lines []string
+			if f.cols > 0 && !f.args.OneLine {
+				lines = f.lines(src.grid)
+			} else if fitted, ok := fitIntoWidth(src.grid.Cells(), f.args.Across, f.args.Separator, f.args.Width); ok && !f.args.OneLine && !f.args.Null {
+				lines = fitted
 			} else {
-				n, err = w.Write([]byte(d.String()))
+				lines = src.grid.Cells()
+			}
+			for _, line := range lines {
+				n, err = w.Write([]byte(line + eol))
 				if total += n; err != nil {
 					break
 				}
 			}
+			if err != nil {
+				break
+			}
 		}
-		if writeNames {
+		if writeNames && !f.args.Null {
 			n, err = w.Write([]byte("\n"))
 			if total += n; err != nil {
 				break
@@ -101,29 +152,105 @@ func (f commonFormatter) WriteTo(w io.Writer) (int64, error) {
 	return int64(total), err
 }
 
+// lines returns the lines of a grid with fixed columns, with the cells
+// padded according to the alignment of their columns and their display
+// width.
+func (f commonFormatter) lines(grid *gridt.Grid) []string {
+	cells := grid.Cells()
+	widths := make([]int, f.cols)
+	for i, cell := range cells {
+		if w := cellWidth(cell); w > widths[i%f.cols] {
+			widths[i%f.cols] = w
+		}
+	}
+	var lines []string
+	for start := 0; start < len(cells); start += f.cols {
+		end := start + f.cols
+		if end > len(cells) {
+			end = len(cells)
+		}
+		var line strings.Builder
+		for col, cell := range cells[start:end] {
+			if col > 0 {
+				line.WriteString(f.args.Separator)
+			}
+			pad := strings.Repeat(" ", widths[col]-cellWidth(cell))
+			switch {
+			case col < len(f.aligns) && f.aligns[col] == alignRight:
+				line.WriteString(pad + cell)
+			case start+col == end-1:
+				line.WriteString(cell)
+			default:
+				line.WriteString(cell + pad)
+			}
+		}
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
 // getName returns the name of the file, based on the arguments.
+// The icon, if any, is colored along with the name.
 func (f commonFormatter) getName(file ipe.File) string {
+	name := f.safe(quoteName(file.Name(), f.args.Quoting))
+	if f.icons != nil {
+		if icon := f.icons.lookup(file); icon != "" {
+			name = icon + " " + name
+		}
+	}
+	name = f.colorName(file, name)
+	if f.args.Classify {
+		name += strings.TrimPrefix(file.ClassifiedName(), file.Name())
+	}
+	return name
+}
+
+// getPlainName returns the name of the file, based on the arguments, but
+// without colors, for the formats that aren't written to the terminal.
+func (f commonFormatter) getPlainName(file ipe.File) string {
+	name := f.safe(quoteName(file.Name(), f.args.Quoting))
 	if f.args.Classify {
-		return file.ClassifiedName()
+		name += strings.TrimPrefix(file.ClassifiedName(), file.Name())
 	}
-	return file.Name()
+	return name
 }
 
+// safe returns the text with the control characters hidden, if needed.
+func (f commonFormatter) safe(text string) string {
+	if f.hideControl {
+		return hideControlChars(text)
+	}
+	return text
+}
+
+// leaveDir is called after all the files of a directory are formatted.
+func (f *commonFormatter) leaveDir(file ipe.File, corners []bool) {}
+
 // appendSource appends another `srcInfo` to its list.
 func (f *commonFormatter) appendSource(src srcInfo) {
 	f.srcs = append(f.srcs, src)
 }
 
 // NewFormatter returns the correct formatter based on the arguments.
+// The sources are read when the formatter is written. The template is only
+// used with the text format, so it doesn't override other formats.
 func NewFormatter(args ArgsInfo) Formatter {
-	if args.Long && args.Tree {
-		return wrap(newLongTreeFormatter(args), args)
-	}
-	if args.Long {
-		return wrap(newLongFormatter(args), args)
-	}
-	if args.Tree {
-		return wrap(newTreeFormatter(args), args)
-	}
-	return wrap(newGridFormatter(args), args)
+	return wrap(func(args ArgsInfo, repos *gitRepos) Formatter {
+		if args.Template != nil && (args.Format == "" || args.Format == ArgFormatText) {
+			return newVisitorFormatter(newTemplateFormatter(args))
+		}
+		if constructor, ok := findFormat(args.Format); ok {
+			return newVisitorFormatter(constructor(args, repos))
+		}
+		if args.Long && args.Tree {
+			return newLongTreeFormatter(args, repos)
+		}
+		if args.Long {
+			return newLongFormatter(args, repos)
+		}
+		if args.Tree {
+			return newTreeFormatter(args, repos)
+		}
+		return newGridFormatter(args, repos)
+	}, args)
 }
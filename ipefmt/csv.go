@@ -0,0 +1,90 @@
+package ipefmt
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/Nhanderu/ipe"
+)
+
+func init() {
+	registerFormat(ArgFormatCSV, func(args ArgsInfo, repos *gitRepos) VisitorFormatter {
+		return newCSVFormatter(args, repos, ',')
+	})
+	registerFormat(ArgFormatTSV, func(args ArgsInfo, repos *gitRepos) VisitorFormatter {
+		return newCSVFormatter(args, repos, '\t')
+	})
+}
+
+// csvFormatter writes the columns of the long view as delimited values,
+// with a header row. Names are relative to the source, so the entries of
+// recursive listings can be told apart.
+type csvFormatter struct {
+	args    ArgsInfo
+	columns []column
+	src     string
+	buffer  bytes.Buffer
+	writer  *csv.Writer
+	err     error
+}
+
+func newCSVFormatter(args ArgsInfo, repos *gitRepos, comma rune) *csvFormatter {
+	f := &csvFormatter{args: args, columns: columnsToShow(args, repos)}
+	f.writer = csv.NewWriter(&f.buffer)
+	f.writer.Comma = comma
+	header := make([]string, len(f.columns))
+	for i, col := range f.columns {
+		header[i] = col.name
+	}
+	f.writer.Write(header)
+	return f
+}
+
+func (f *csvFormatter) EnterDir(dir ipe.File, corners []bool) {
+	if len(corners) == 0 {
+		f.src = dir.FullName()
+	}
+}
+
+func (f *csvFormatter) VisitFile(file ipe.File, corners []bool) {
+	record := make([]string, len(f.columns))
+	for i, col := range f.columns {
+		switch {
+		case col.value == nil:
+			record[i], _ = filepath.Rel(f.src, file.FullName())
+			if f.args.Human && f.args.Classify {
+				record[i] += strings.TrimPrefix(file.ClassifiedName(), file.Name())
+			}
+		case f.args.Human:
+			record[i] = col.value(file, f.args)
+		default:
+			record[i] = col.rawValue(file, f.args)
+		}
+	}
+	f.writer.Write(record)
+}
+
+func (f *csvFormatter) LeaveDir(dir ipe.File, corners []bool) {}
+
+// Error keeps the first error, so it's returned by `WriteTo`, as it can't
+// be part of the values.
+func (f *csvFormatter) Error(err error) {
+	if f.err == nil {
+		f.err = err
+	}
+}
+
+func (f *csvFormatter) WriteTo(w io.Writer) (int64, error) {
+	f.writer.Flush()
+	if err := f.writer.Error(); err != nil {
+		return 0, err
+	}
+	n, err := bytes.NewReader(f.buffer.Bytes()).WriteTo(w)
+	if err != nil {
+		return n, err
+	}
+	return n, f.err
+}
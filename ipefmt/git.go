@@ -0,0 +1,321 @@
+package ipefmt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Nhanderu/ipe"
+	"github.com/Nhanderu/ipe/git"
+)
+
+// gitRepos finds the repositories of the files and keeps what was read from
+// them, so each repository is read once per output and each file is looked
+// up once. The wrapper creates one for every output and gives it to the
+// formatters; it isn't safe for concurrent use. A nil `gitRepos` knows
+// nothing about the files.
+type gitRepos struct {
+	status  bool
+	commits bool
+	header  bool
+	ignore  bool
+	dirs    map[string]*gitRepo
+	roots   map[string]*gitRepo
+	files   map[string]*gitFile
+}
+
+// gitRepo represents a repository, with the status, the ignored files and
+// the history read when first needed. They're nil if they couldn't be read.
+type gitRepo struct {
+	repo        *git.Repository
+	status      *git.Status
+	statusRead  bool
+	ignore      *git.Ignore
+	ignoreRead  bool
+	history     *git.History
+	historyRead bool
+}
+
+// gitFile is what the repository says about a file, for the information
+// asked for in the arguments.
+type gitFile struct {
+	status    git.FileStatus
+	hasStatus bool
+	commit    *git.Commit
+	ignored   bool
+}
+
+// newGitRepos returns the repositories for an output, or nil if nothing
+// from them was asked for.
+func newGitRepos(args ArgsInfo) *gitRepos {
+	if !args.Git && !args.GitCommit && !args.GitHeader && !args.GitIgnore {
+		return nil
+	}
+	return &gitRepos{
+		status:  args.Git,
+		commits: args.GitCommit,
+		header:  args.GitHeader,
+		ignore:  args.GitIgnore,
+		dirs:    make(map[string]*gitRepo),
+		roots:   make(map[string]*gitRepo),
+		files:   make(map[string]*gitFile),
+	}
+}
+
+// find returns the repository containing the directory, or nil if there's
+// none.
+func (g *gitRepos) find(dir string) *gitRepo {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+	return g.lookup(dir)
+}
+
+// lookup is like find, for absolute paths. Only the directories with a
+// `.git` entry are opened; the others have the repository of their parent,
+// so each directory is looked up once, even outside of repositories.
+func (g *gitRepos) lookup(dir string) *gitRepo {
+	if r, ok := g.dirs[dir]; ok {
+		return r
+	}
+	var found *gitRepo
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		if repo, err := git.Open(dir); err == nil {
+			var ok bool
+			if found, ok = g.roots[repo.WorkTree]; !ok {
+				found = &gitRepo{repo: repo}
+				g.roots[repo.WorkTree] = found
+			}
+		}
+	} else if parent := filepath.Dir(dir); parent != dir {
+		found = g.lookup(parent)
+	}
+	g.dirs[dir] = found
+	return found
+}
+
+func (r *gitRepo) getStatus() *git.Status {
+	if !r.statusRead {
+		r.status, _ = r.repo.Status()
+		r.statusRead = true
+	}
+	return r.status
+}
+
+func (r *gitRepo) getIgnore() *git.Ignore {
+	if !r.ignoreRead {
+		r.ignore, _ = r.repo.Ignore()
+		r.ignoreRead = true
+	}
+	return r.ignore
+}
+
+func (r *gitRepo) getHistory() *git.History {
+	if !r.historyRead {
+		r.history, _ = r.repo.History()
+		r.historyRead = true
+	}
+	return r.history
+}
+
+// noGitFile is what's known about the files when nothing from the
+// repositories was asked for.
+var noGitFile gitFile
+
+// file returns what the repository of the file says about it, reading it
+// the first time the file is looked up.
+func (g *gitRepos) file(file ipe.File) *gitFile {
+	if g == nil {
+		return &noGitFile
+	}
+	if info, ok := g.files[file.FullName()]; ok {
+		return info
+	}
+	info := &gitFile{}
+	g.files[file.FullName()] = info
+	r := g.find(filepath.Dir(file.FullName()))
+	if r == nil {
+		return info
+	}
+	if g.status && r.getStatus() != nil {
+		if file.IsDir() {
+			info.status = r.status.Dir(file.FullName())
+		} else {
+			info.status = r.status.File(file.FullName())
+		}
+		info.hasStatus = true
+	}
+	if g.commits && r.getHistory() != nil {
+		info.commit, _ = r.history.Last(file.FullName())
+	}
+	if g.ignore && r.getIgnore() != nil {
+		info.ignored = r.ignore.Match(file.FullName(), file.IsDir())
+	}
+	return info
+}
+
+// gitStatus returns the status of the file in its repository. Directories
+// have the summarized status of their files. It returns false if the file
+// isn't in a repository or the status wasn't asked for.
+func (g *gitRepos) gitStatus(file ipe.File) (git.FileStatus, bool) {
+	info := g.file(file)
+	return info.status, info.hasStatus
+}
+
+// gitIgnored reports whether the file is ignored by its repository, if
+// ignored files should be hidden or dimmed.
+func (g *gitRepos) gitIgnored(file ipe.File) bool {
+	return g.file(file).ignored
+}
+
+// lastCommit returns the last commit that changed the file, or the newest
+// one under it for directories. It returns false if the file isn't in the
+// history or the commits weren't asked for.
+func (g *gitRepos) lastCommit(file ipe.File) (*git.Commit, bool) {
+	c := g.file(file).commit
+	return c, c != nil
+}
+
+// gitHeader returns a line describing the repository of the source: its
+// root, the current branch or the detached commit, how far it's ahead and
+// behind the upstream branch and whether it's dirty. It returns false if the
+// source isn't in a repository or the header wasn't asked for.
+func (g *gitRepos) gitHeader(src ipe.File) (string, bool) {
+	if g == nil || !g.header {
+		return "", false
+	}
+	dir := src.FullName()
+	if !src.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	r := g.find(dir)
+	if r == nil {
+		return "", false
+	}
+	branch, err := r.repo.Branch()
+	if err != nil {
+		return "", false
+	}
+	var b strings.Builder
+	b.WriteString(r.repo.WorkTree + ": ")
+	switch {
+	case branch.Detached():
+		fmt.Fprintf(&b, "HEAD (detached at %s)", shortHash(branch.Head))
+	case branch.Head.IsZero():
+		fmt.Fprintf(&b, "%s (no commits)", branch.Name)
+	default:
+		b.WriteString(branch.Name)
+	}
+	if branch.Upstream != "" {
+		b.WriteString("..." + branch.Upstream)
+		switch {
+		case branch.Ahead > 0 && branch.Behind > 0:
+			fmt.Fprintf(&b, " [ahead %d, behind %d]", branch.Ahead, branch.Behind)
+		case branch.Ahead > 0:
+			fmt.Fprintf(&b, " [ahead %d]", branch.Ahead)
+		case branch.Behind > 0:
+			fmt.Fprintf(&b, " [behind %d]", branch.Behind)
+		}
+	}
+	if st := r.getStatus(); st != nil && st.IsDirty() {
+		b.WriteString(", dirty")
+	}
+	return b.String(), true
+}
+
+// fmtGitStatus returns the two letters of the status of the file, with
+// dashes for the unmodified ones, or an empty string if it isn't in a
+// repository.
+func fmtGitStatus(st git.FileStatus, ok bool) string {
+	if !ok {
+		return ""
+	}
+	return strings.Replace(st.String(), " ", "-", -1)
+}
+
+// fmtRawGitStatus returns the status of the file like the short format of
+// `git status`.
+func fmtRawGitStatus(st git.FileStatus, ok bool) string {
+	if !ok {
+		return ""
+	}
+	return st.String()
+}
+
+// fmtGitCommit returns the date, the short hash and the author of the last
+// commit of the file, or an empty string if it has none.
+func fmtGitCommit(c *git.Commit, args ArgsInfo) string {
+	if c == nil {
+		return ""
+	}
+	return fmtTime(c.Time, args) + " " + shortHash(c.Hash) + " " + c.Author
+}
+
+// shortHash returns the abbreviated hash of a commit, like Git shows it.
+func shortHash(h git.Hash) string {
+	return h.String()[:7]
+}
+
+// fmtRawGitCommit returns the last commit of the file with the date in
+// RFC 3339 and the full hash, so it's sorted by date.
+func fmtRawGitCommit(c *git.Commit, args ArgsInfo) string {
+	if c == nil {
+		return ""
+	}
+	return fmtRawTime(c.Time, args) + " " + c.Hash.String() + " " + c.Author
+}
+
+// gitStatusColumn returns the Git status column for the repositories.
+func gitStatusColumn(repos *gitRepos, _ ArgsInfo) column {
+	return column{
+		value: func(file ipe.File, args ArgsInfo) string {
+			return fmtGitStatus(repos.gitStatus(file))
+		},
+		raw: func(file ipe.File, args ArgsInfo) string {
+			return fmtRawGitStatus(repos.gitStatus(file))
+		},
+		paint: func(file ipe.File, value string, p painter) string {
+			return p.paintGit(value)
+		},
+		less: func(a, b ipe.File) bool {
+			return fmtRawGitStatus(repos.gitStatus(a)) < fmtRawGitStatus(repos.gitStatus(b))
+		},
+	}
+}
+
+// gitCommitColumn returns the last commit column for the repositories.
+// Files without commits are sorted first.
+func gitCommitColumn(repos *gitRepos, args ArgsInfo) column {
+	return column{
+		value: func(file ipe.File, args ArgsInfo) string {
+			c, _ := repos.lastCommit(file)
+			return fmtGitCommit(c, args)
+		},
+		raw: func(file ipe.File, args ArgsInfo) string {
+			c, _ := repos.lastCommit(file)
+			return fmtRawGitCommit(c, args)
+		},
+		paint: func(file ipe.File, value string, p painter) string {
+			if c, ok := repos.lastCommit(file); ok {
+				return p.paintCommit(c, fmtTime(c.Time, args))
+			}
+			return value
+		},
+		less: func(a, b ipe.File) bool {
+			ca, _ := repos.lastCommit(a)
+			cb, _ := repos.lastCommit(b)
+			return cb != nil && (ca == nil || ca.Time.Before(cb.Time))
+		},
+	}
+}
+
+// gitMarker returns the status of the file followed by a space, for the
+// views without the long view columns.
+func (f commonFormatter) gitMarker(file ipe.File) string {
+	if status := fmtGitStatus(f.repos.gitStatus(file)); status != "" {
+		return f.painter.paintGit(status) + " "
+	}
+	return ""
+}
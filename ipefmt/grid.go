@@ -10,16 +10,16 @@ type gridFormatter struct {
 	direction gridt.Direction
 }
 
-func newGridFormatter(args ArgsInfo) *gridFormatter {
+func newGridFormatter(args ArgsInfo, repos *gitRepos) *gridFormatter {
 	if args.Across {
-		return &gridFormatter{&commonFormatter{args, make([]srcInfo, 0), 0}, gridt.LeftToRight}
+		return &gridFormatter{newCommonFormatter(args, 0, repos), gridt.LeftToRight}
 	}
-	return &gridFormatter{&commonFormatter{args, make([]srcInfo, 0), 0}, gridt.TopToBottom}
+	return &gridFormatter{newCommonFormatter(args, 0, repos), gridt.TopToBottom}
 }
 
 func (f *gridFormatter) getDir(file ipe.File, grid **gridt.Grid, corners []bool) {
 	*grid = gridt.New(f.direction, f.args.Separator)
-	f.srcs = append(f.srcs, srcInfo{file, nil, *grid})
+	f.srcs = append(f.srcs, srcInfo{file, nil, *grid, len(corners) > 0})
 }
 
 func (f *gridFormatter) getFile(file ipe.File, grid *gridt.Grid, corners []bool) {
@@ -0,0 +1,106 @@
+package ipefmt
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Nhanderu/ipe"
+)
+
+// newTestDir creates a temporary directory with the files, by their paths
+// relative to it. Paths ending with "/" are directories, the contents
+// starting with "->" are the targets of links, and the other contents are
+// written to regular files, executable if the path ends with "*".
+func newTestDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "ipe-fmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for name, content := range files {
+		perm := os.FileMode(0644)
+		if strings.HasSuffix(name, "*") {
+			name, perm = strings.TrimSuffix(name, "*"), 0755
+		}
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case strings.HasSuffix(name, "/"):
+			err = os.MkdirAll(path, 0755)
+		case strings.HasPrefix(content, "->"):
+			err = os.Symlink(content[2:], path)
+		default:
+			err = ioutil.WriteFile(path, []byte(content), perm)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// readTestFile reads a file of the test directory from its parent
+// directory, so links aren't followed.
+func readTestFile(t *testing.T, dir, name string) ipe.File {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	files, err := ipe.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		if file.Name() == filepath.Base(path) {
+			return file
+		}
+	}
+	t.Fatalf("%s not found", name)
+	return ipe.File{}
+}
+
+// pathVisitor collects the paths of the visited files, relative to the
+// root.
+type pathVisitor struct {
+	root  string
+	paths []string
+	errs  []error
+}
+
+func (v *pathVisitor) EnterDir(dir ipe.File, corners []bool) {}
+
+func (v *pathVisitor) VisitFile(file ipe.File, corners []bool) {
+	rel, _ := filepath.Rel(v.root, file.FullName())
+	v.paths = append(v.paths, filepath.ToSlash(rel))
+}
+
+func (v *pathVisitor) LeaveDir(dir ipe.File, corners []bool) {}
+
+func (v *pathVisitor) Error(err error) { v.errs = append(v.errs, err) }
+
+func (v *pathVisitor) WriteTo(w io.Writer) (int64, error) { return 0, nil }
+
+// listPaths lists the directory with the arguments and returns the paths
+// of the entries that are shown, relative to it and sorted.
+func listPaths(t *testing.T, dir string, args ArgsInfo) []string {
+	t.Helper()
+	v := &pathVisitor{root: dir}
+	args.Sources = []string{dir}
+	f := wrap(func(args ArgsInfo, repos *gitRepos) Formatter {
+		return newVisitorFormatter(v)
+	}, args)
+	if _, err := f.WriteTo(ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range v.errs {
+		t.Error(err)
+	}
+	sort.Strings(v.paths)
+	return v.paths
+}
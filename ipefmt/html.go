@@ -0,0 +1,200 @@
+package ipefmt
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/Nhanderu/ipe"
+)
+
+func init() {
+	registerFormat(ArgFormatHTML, func(args ArgsInfo, repos *gitRepos) VisitorFormatter {
+		return newHTMLFormatter(args, repos)
+	})
+}
+
+const htmlStyle = `body { font-family: monospace; margin: 2em; }
+details { margin-left: 1.5em; }
+summary { cursor: pointer; }
+ul.tree { list-style: none; padding-left: 1.5em; margin: 0; }
+table { border-collapse: collapse; margin: .5em 0 .5em 1.5em; }
+th { cursor: pointer; text-align: left; border-bottom: 1px solid #999; user-select: none; }
+th[data-order=asc]::after { content: " \25B4"; }
+th[data-order=desc]::after { content: " \25BE"; }
+td, th { padding: 0 .75em; white-space: pre; }
+td.right { text-align: right; }
+.error { color: #cc0000; }
+`
+
+const htmlScript = `document.querySelectorAll("table.sortable th").forEach(function (th) {
+	th.addEventListener("click", function () {
+		var table = th.closest("table"), body = table.tBodies[0], i = th.cellIndex;
+		var asc = th.dataset.order !== "asc";
+		table.querySelectorAll("th").forEach(function (h) { delete h.dataset.order; });
+		th.dataset.order = asc ? "asc" : "desc";
+		var number = /^-?\d+(\.\d+)?$/;
+		Array.from(body.rows).sort(function (a, b) {
+			var x = a.cells[i].dataset.sort, y = b.cells[i].dataset.sort;
+			var c = number.test(x) && number.test(y) ? x - y : x.localeCompare(y);
+			return asc ? c : -c;
+		}).forEach(function (row) { body.appendChild(row); });
+	});
+});
+`
+
+// htmlNode represents an entry in the HTML report.
+type htmlNode struct {
+	file     ipe.File
+	children []*htmlNode
+}
+
+// htmlFormatter writes the entries as a standalone HTML page, with
+// collapsible directories. In long view, every directory has a table with
+// the long view columns, sortable by clicking their headers.
+type htmlFormatter struct {
+	*commonFormatter
+	columns []column
+	roots   []*htmlNode
+	stack   []*htmlNode
+	last    *htmlNode
+	errs    []error
+}
+
+func newHTMLFormatter(args ArgsInfo, repos *gitRepos) *htmlFormatter {
+	common := newCommonFormatter(args, 0, repos)
+	common.painter.html = true
+	return &htmlFormatter{commonFormatter: common, columns: columnsToShow(args, repos)}
+}
+
+func (f *htmlFormatter) EnterDir(dir ipe.File, corners []bool) {
+	if len(corners) == 0 {
+		f.last = &htmlNode{file: dir}
+		f.roots = append(f.roots, f.last)
+	}
+	f.stack = append(f.stack, f.last)
+}
+
+func (f *htmlFormatter) VisitFile(file ipe.File, corners []bool) {
+	f.last = &htmlNode{file: file}
+	parent := f.stack[len(f.stack)-1]
+	parent.children = append(parent.children, f.last)
+}
+
+func (f *htmlFormatter) LeaveDir(dir ipe.File, corners []bool) {
+	f.stack = f.stack[:len(f.stack)-1]
+}
+
+func (f *htmlFormatter) Error(err error) {
+	f.errs = append(f.errs, err)
+}
+
+func (f htmlFormatter) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	title := html.EscapeString(strings.Join(f.args.Sources, " "))
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n", title)
+	fmt.Fprintf(&b, "<style>\n%s%s</style>\n</head>\n<body>\n", htmlStyle, themeCSS(f.painter.theme))
+	for _, err := range f.errs {
+		fmt.Fprintf(&b, "<p class=\"error\">%s</p>\n", html.EscapeString(err.Error()))
+	}
+	for _, root := range f.roots {
+		if f.args.Long {
+			f.writeTable(&b, root, root.file.FullName())
+		} else {
+			fmt.Fprint(&b, "<ul class=\"tree\">\n")
+			f.writeList(&b, root, root.file.FullName())
+			fmt.Fprint(&b, "</ul>\n")
+		}
+	}
+	fmt.Fprintf(&b, "<script>\n%s</script>\n</body>\n</html>\n", htmlScript)
+	return b.WriteTo(w)
+}
+
+// writeList writes the node as an item of a nested list.
+func (f htmlFormatter) writeList(b *bytes.Buffer, node *htmlNode, name string) {
+	if len(node.children) == 0 {
+		fmt.Fprintf(b, "<li>%s</li>\n", f.htmlName(node.file, name))
+		return
+	}
+	fmt.Fprintf(b, "<li><details open><summary>%s</summary>\n<ul class=\"tree\">\n", f.htmlName(node.file, name))
+	for _, child := range node.children {
+		f.writeList(b, child, f.getPlainName(child.file))
+	}
+	fmt.Fprint(b, "</ul>\n</details></li>\n")
+}
+
+// writeTable writes the node as a table of its children, followed by the
+// tables of its subdirectories.
+func (f htmlFormatter) writeTable(b *bytes.Buffer, node *htmlNode, name string) {
+	fmt.Fprintf(b, "<details open><summary>%s</summary>\n<table class=\"sortable\">\n<thead><tr>", f.htmlName(node.file, name))
+	for _, col := range f.columns {
+		fmt.Fprintf(b, "<th>%s</th>", f.painter.paint("header", col.name))
+	}
+	fmt.Fprint(b, "</tr></thead>\n<tbody>\n")
+	for _, child := range node.children {
+		fmt.Fprint(b, "<tr>")
+		for _, col := range f.columns {
+			var class string
+			if columnAlign(col, f.args) == alignRight {
+				class = " class=\"right\""
+			}
+			if col.value == nil {
+				fmt.Fprintf(b, "<td data-sort=\"%s\">%s</td>",
+					html.EscapeString(child.file.Name()),
+					f.htmlName(child.file, f.getPlainName(child.file)))
+			} else {
+				value := col.value(child.file, f.args)
+				if col.paint != nil {
+					value = col.paint(child.file, value, f.painter)
+				} else {
+					value = html.EscapeString(value)
+				}
+				fmt.Fprintf(b, "<td%s data-sort=\"%s\">%s</td>",
+					class,
+					html.EscapeString(col.rawValue(child.file, f.args)),
+					value)
+			}
+		}
+		fmt.Fprint(b, "</tr>\n")
+	}
+	fmt.Fprint(b, "</tbody>\n</table>\n")
+	for _, child := range node.children {
+		if len(child.children) > 0 {
+			f.writeTable(b, child, f.getPlainName(child.file))
+		}
+	}
+	fmt.Fprint(b, "</details>\n")
+}
+
+// htmlName returns the name of the file, escaped and colored with the same
+// colors as the terminal. Invalid bytes are replaced, since the page is
+// encoded in UTF-8.
+func (f htmlFormatter) htmlName(file ipe.File, name string) string {
+	name = strings.ToValidUTF8(name, "\uFFFD")
+	return fmt.Sprintf("<span class=\"%s\" style=\"%s\">%s</span>",
+		fileClass(file),
+		sgrToCSS(f.colors.lookup(file)),
+		html.EscapeString(name))
+}
+
+// htmlSpan escapes the text and wraps it in a span with the class, for the
+// styles of the theme.
+func htmlSpan(class, text string) string {
+	if class == "" {
+		return html.EscapeString(text)
+	}
+	return fmt.Sprintf("<span class=\"%s\">%s</span>", class, html.EscapeString(text))
+}
+
+// themeCSS returns the CSS rules of the classes of the theme styles.
+func themeCSS(theme Theme) string {
+	var b strings.Builder
+	for _, style := range themeStyles {
+		if css := sgrToCSS(theme[style]); css != "" {
+			fmt.Fprintf(&b, ".%s { %s; }\n", style, css)
+		}
+	}
+	return b.String()
+}
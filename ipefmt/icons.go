@@ -0,0 +1,156 @@
+package ipefmt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Nhanderu/ipe"
+)
+
+// IconSet represents the icons shown before the names, by well-known name,
+// by extension and by file type. The icons are Nerd Fonts v3 glyphs, which
+// are in the private use areas of Unicode and take a single cell, like the
+// other characters. The Material Design ones are after U+F0000, where
+// Nerd Fonts v3 moved them.
+type IconSet struct {
+	names map[string]string
+	exts  map[string]string
+	types map[string]string
+}
+
+var defaultIconTypes = map[string]string{
+	"di": "\uf115",
+	"ln": "\uf481",
+	"ex": "\uf489",
+	"pi": "\U000f0232",
+	"so": "\U000f01a8",
+	"bd": "\uf0a0",
+	"cd": "\ue601",
+	"fi": "\uf016",
+}
+
+var defaultIconNames = map[string]string{
+	".git":               "\ue5fb",
+	".gitattributes":     "\uf1d3",
+	".gitignore":         "\uf1d3",
+	".gitmodules":        "\uf1d3",
+	"dockerfile":         "\uf308",
+	"docker-compose.yml": "\uf308",
+	"go.mod":             "\ue626",
+	"go.sum":             "\ue626",
+	"license":            "\U000f0219",
+	"makefile":           "\ue779",
+	"node_modules":       "\ue718",
+	"readme":             "\uf48a",
+}
+
+var defaultIconExts = map[string]string{
+	"7z":   "\uf410",
+	"c":    "\ue61e",
+	"cpp":  "\ue61d",
+	"css":  "\ue749",
+	"gif":  "\uf1c5",
+	"go":   "\ue626",
+	"gz":   "\uf410",
+	"h":    "\uf0fd",
+	"html": "\uf13b",
+	"java": "\ue204",
+	"jpg":  "\uf1c5",
+	"js":   "\ue74e",
+	"json": "\ue60b",
+	"lock": "\uf023",
+	"md":   "\uf48a",
+	"mp3":  "\uf001",
+	"mp4":  "\uf03d",
+	"pdf":  "\uf1c1",
+	"png":  "\uf1c5",
+	"py":   "\ue606",
+	"rb":   "\ue21e",
+	"rs":   "\ue7a8",
+	"sh":   "\uf489",
+	"svg":  "\uf1c5",
+	"tar":  "\uf410",
+	"toml": "\ue615",
+	"ts":   "\ue628",
+	"txt":  "\uf15c",
+	"xml":  "\uf05c",
+	"yaml": "\uf1c9",
+	"yml":  "\uf1c9",
+	"zip":  "\uf410",
+}
+
+// DefaultIconSet returns the built-in icons.
+func DefaultIconSet() *IconSet {
+	s := &IconSet{make(map[string]string), make(map[string]string), make(map[string]string)}
+	for k, v := range defaultIconNames {
+		s.names[k] = v
+	}
+	for k, v := range defaultIconExts {
+		s.exts[k] = v
+	}
+	for k, v := range defaultIconTypes {
+		s.types[k] = v
+	}
+	return s
+}
+
+// ParseIconSet parses an icons file, overriding the built-in icons. Each
+// line has a key and an icon, separated by spaces. The key is an extension,
+// like "*.go", a file type keyword of the `dircolors` database, like "DIR",
+// or a file name, like "Makefile". The icon can be written as is or
+// escaped, like "\uf115" or "\U000f0232". Lines starting with "#" are comments.
+func ParseIconSet(r io.Reader) (*IconSet, error) {
+	s := DefaultIconSet()
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a key and an icon", n)
+		}
+		key, icon := fields[0], fields[1]
+		if unquoted, err := strconv.Unquote(`"` + icon + `"`); err == nil {
+			icon = unquoted
+		}
+		switch class, ok := dircolorsKeywords[key]; {
+		case strings.HasPrefix(key, "*."):
+			s.exts[strings.ToLower(key[2:])] = icon
+		case ok:
+			s.types[class] = icon
+		default:
+			s.names[strings.ToLower(key)] = icon
+		}
+	}
+	return s, scanner.Err()
+}
+
+// lookup returns the icon of the file. Well-known names come first, then
+// extensions of regular files, then file types.
+func (s *IconSet) lookup(file ipe.File) string {
+	name := strings.ToLower(file.Name())
+	if icon, ok := s.names[name]; ok {
+		return icon
+	}
+	class := fileClass(file)
+	if class == "fi" || class == "ex" {
+		if ext := strings.TrimPrefix(filepath.Ext(name), "."); ext != "" {
+			if icon, ok := s.exts[ext]; ok {
+				return icon
+			}
+		}
+	}
+	for class != "" {
+		if icon, ok := s.types[class]; ok {
+			return icon
+		}
+		class = fallbackClasses[class]
+	}
+	return ""
+}
@@ -0,0 +1,190 @@
+package ipefmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Nhanderu/ipe"
+)
+
+func init() {
+	registerFormat(ArgFormatJSON, func(args ArgsInfo, repos *gitRepos) VisitorFormatter {
+		return newJSONFormatter(args, repos)
+	})
+	registerFormat(ArgFormatNDJSON, func(args ArgsInfo, repos *gitRepos) VisitorFormatter {
+		return newNDJSONFormatter(args, repos)
+	})
+}
+
+// jsonEntry represents an entry in the JSON output.
+// Names that aren't valid UTF-8 are also kept as bytes, since JSON strings
+// can't represent them.
+type jsonEntry struct {
+	Type      string       `json:"type"`
+	Name      string       `json:"name"`
+	NameBytes []byte       `json:"name_bytes,omitempty"`
+	Path      string       `json:"path"`
+	PathBytes []byte       `json:"path_bytes,omitempty"`
+	Target    string       `json:"target,omitempty"`
+	Size      int64        `json:"size"`
+	Mode      string       `json:"mode"`
+	Perm      string       `json:"perm"`
+	Inode     uint64       `json:"inode"`
+	Links     uint64       `json:"links"`
+	Blocks    int64        `json:"blocks"`
+	User      string       `json:"user"`
+	UID       string       `json:"uid"`
+	Group     string       `json:"group"`
+	GID       string       `json:"gid"`
+	Accessed  time.Time    `json:"accessed"`
+	Modified  time.Time    `json:"modified"`
+	Created   time.Time    `json:"created"`
+	Git       string       `json:"git,omitempty"`
+	Commit    *jsonCommit  `json:"commit,omitempty"`
+	Contents  []*jsonEntry `json:"contents,omitempty"`
+}
+
+func newJSONEntry(file ipe.File, args ArgsInfo, repos *gitRepos) *jsonEntry {
+	loc := args.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	e := &jsonEntry{
+		Type:     fileType(file),
+		Name:     file.Name(),
+		Path:     file.FullName(),
+		Size:     file.Size(),
+		Mode:     file.Mode().String(),
+		Perm:     fmt.Sprintf("%04o", file.Mode().Perm()),
+		Inode:    file.Inode(),
+		Links:    file.Links(),
+		Blocks:   file.Blocks(),
+		Accessed: file.AccTime().In(loc),
+		Modified: file.ModTime().In(loc),
+		Created:  file.CrtTime().In(loc),
+		Git:      fmtRawGitStatus(repos.gitStatus(file)),
+	}
+	if !utf8.ValidString(e.Name) {
+		e.NameBytes = []byte(e.Name)
+	}
+	if !utf8.ValidString(e.Path) {
+		e.PathBytes = []byte(e.Path)
+	}
+	if file.IsSymlink() {
+		e.Target, _ = os.Readlink(file.FullName())
+	}
+	if u := file.User(); u != nil {
+		e.User, e.UID = u.Username, u.Uid
+	}
+	if g := file.Group(); g != nil {
+		e.Group, e.GID = g.Name, g.Gid
+	}
+	if c, ok := repos.lastCommit(file); ok {
+		e.Commit = &jsonCommit{
+			Hash:   c.Hash.String(),
+			Author: c.Author,
+			Email:  c.Email,
+			Time:   c.Time.In(loc),
+		}
+	}
+	return e
+}
+
+// jsonCommit represents the last commit of an entry.
+type jsonCommit struct {
+	Hash   string    `json:"hash"`
+	Author string    `json:"author"`
+	Email  string    `json:"email"`
+	Time   time.Time `json:"time"`
+}
+
+// jsonFormatter writes the entries as nested JSON objects, mirroring the
+// tree, followed by a report, like `tree -J`.
+type jsonFormatter struct {
+	args  ArgsInfo
+	repos *gitRepos
+	nodes []interface{}
+	stack []*jsonEntry
+	last  *jsonEntry
+	dirs  int
+	files int
+}
+
+func newJSONFormatter(args ArgsInfo, repos *gitRepos) *jsonFormatter {
+	return &jsonFormatter{args: args, repos: repos}
+}
+
+func (f *jsonFormatter) EnterDir(dir ipe.File, corners []bool) {
+	if len(corners) == 0 {
+		f.last = newJSONEntry(dir, f.args, f.repos)
+		f.nodes = append(f.nodes, f.last)
+	}
+	f.stack = append(f.stack, f.last)
+}
+
+func (f *jsonFormatter) VisitFile(file ipe.File, corners []bool) {
+	f.last = newJSONEntry(file, f.args, f.repos)
+	parent := f.stack[len(f.stack)-1]
+	parent.Contents = append(parent.Contents, f.last)
+	if file.IsDir() {
+		f.dirs++
+	} else {
+		f.files++
+	}
+}
+
+func (f *jsonFormatter) LeaveDir(dir ipe.File, corners []bool) {
+	f.stack = f.stack[:len(f.stack)-1]
+}
+
+func (f *jsonFormatter) Error(err error) {
+	f.nodes = append(f.nodes, map[string]string{"type": "error", "error": err.Error()})
+}
+
+func (f jsonFormatter) WriteTo(w io.Writer) (int64, error) {
+	nodes := append(f.nodes, map[string]interface{}{
+		"type":        "report",
+		"directories": f.dirs,
+		"files":       f.files,
+	})
+	var buffer bytes.Buffer
+	if err := json.NewEncoder(&buffer).Encode(nodes); err != nil {
+		return 0, err
+	}
+	return buffer.WriteTo(w)
+}
+
+// ndjsonFormatter writes one JSON object per line for every entry.
+type ndjsonFormatter struct {
+	args    ArgsInfo
+	repos   *gitRepos
+	buffer  bytes.Buffer
+	encoder *json.Encoder
+}
+
+func newNDJSONFormatter(args ArgsInfo, repos *gitRepos) *ndjsonFormatter {
+	f := &ndjsonFormatter{args: args, repos: repos}
+	f.encoder = json.NewEncoder(&f.buffer)
+	return f
+}
+
+func (f *ndjsonFormatter) EnterDir(dir ipe.File, corners []bool) {}
+
+func (f *ndjsonFormatter) VisitFile(file ipe.File, corners []bool) {
+	f.encoder.Encode(newJSONEntry(file, f.args, f.repos))
+}
+
+func (f *ndjsonFormatter) LeaveDir(dir ipe.File, corners []bool) {}
+
+func (f *ndjsonFormatter) Error(err error) {
+	f.encoder.Encode(map[string]string{"type": "error", "error": err.Error()})
+}
+
+func (f *ndjsonFormatter) WriteTo(w io.Writer) (int64, error) {
+	return bytes.NewReader(f.buffer.Bytes()).WriteTo(w)
+}
@@ -0,0 +1,179 @@
+package ipefmt
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const zeroWidthJoiner = '\u200D'
+
+// wideRanges are the ranges of characters that take two cells in the
+// terminal: the East Asian Wide and Fullwidth characters and the emojis
+// presented as such.
+var wideRanges = [][2]rune{
+	{0x1100, 0x115F}, {0x231A, 0x231B}, {0x2329, 0x232A}, {0x23E9, 0x23EC},
+	{0x23F0, 0x23F0}, {0x23F3, 0x23F3}, {0x25FD, 0x25FE}, {0x2614, 0x2615},
+	{0x2648, 0x2653}, {0x267F, 0x267F}, {0x2693, 0x2693}, {0x26A1, 0x26A1},
+	{0x26AA, 0x26AB}, {0x26BD, 0x26BE}, {0x26C4, 0x26C5}, {0x26CE, 0x26CE},
+	{0x26D4, 0x26D4}, {0x26EA, 0x26EA}, {0x26F2, 0x26F3}, {0x26F5, 0x26F5},
+	{0x26FA, 0x26FA}, {0x26FD, 0x26FD}, {0x2705, 0x2705}, {0x270A, 0x270B},
+	{0x2728, 0x2728}, {0x274C, 0x274C}, {0x274E, 0x274E}, {0x2753, 0x2755},
+	{0x2757, 0x2757}, {0x2795, 0x2797}, {0x27B0, 0x27B0}, {0x27BF, 0x27BF},
+	{0x2B1B, 0x2B1C}, {0x2B50, 0x2B50}, {0x2B55, 0x2B55}, {0x2E80, 0x303E},
+	{0x3041, 0x33FF}, {0x3400, 0x4DBF}, {0x4E00, 0x9FFF}, {0xA000, 0xA4CF},
+	{0xA960, 0xA97F}, {0xAC00, 0xD7A3}, {0xF900, 0xFAFF}, {0xFE10, 0xFE19},
+	{0xFE30, 0xFE6F}, {0xFF00, 0xFF60}, {0xFFE0, 0xFFE6}, {0x16FE0, 0x16FE4},
+	{0x17000, 0x18AFF}, {0x1B000, 0x1B2FF}, {0x1F004, 0x1F004}, {0x1F0CF, 0x1F0CF},
+	{0x1F18E, 0x1F18E}, {0x1F191, 0x1F19A}, {0x1F200, 0x1F251}, {0x1F300, 0x1F64F},
+	{0x1F680, 0x1F6FF}, {0x1F7E0, 0x1F7EB}, {0x1F900, 0x1F9FF}, {0x1FA70, 0x1FAFF},
+	{0x20000, 0x2FFFD}, {0x30000, 0x3FFFD},
+}
+
+// cellWidth returns the number of terminal cells the text takes.
+// Escape sequences, combining marks and format characters take none, East Asian wide
+// characters and emojis take two, and the characters joined to the
+// previous one by a zero width joiner don't add to its width.
+func cellWidth(s string) int {
+	var width int
+	var joined bool
+	for i := 0; i < len(s); {
+		if n := escapeLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		w := runeWidth(r)
+		if joined {
+			w = 0
+		}
+		joined = r == zeroWidthJoiner
+		width += w
+	}
+	return width
+}
+
+// escapeLen returns the length of the ANSI escape sequence (CSI) the text
+// starts with, or zero if it doesn't start with one.
+func escapeLen(s string) int {
+	if len(s) < 2 || s[0] != '\x1b' || s[1] != '[' {
+		return 0
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7E {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func runeWidth(r rune) int {
+	switch {
+	case r == utf8.RuneError:
+		return 1
+	case r < 0x20 || (r >= 0x7F && r < 0xA0):
+		return 0
+	case r >= 0x1160 && r <= 0x11FF:
+		// Hangul medial vowels and final consonants join the previous syllable.
+		return 0
+	case r >= 0x1F3FB && r <= 0x1F3FF:
+		// Skin tone modifiers are drawn with the previous emoji.
+		return 0
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case isWide(r):
+		return 2
+	default:
+		return 1
+	}
+}
+
+func isWide(r rune) bool {
+	i := sort.Search(len(wideRanges), func(i int) bool { return wideRanges[i][1] >= r })
+	return i < len(wideRanges) && wideRanges[i][0] <= r
+}
+
+// fitIntoWidth lays the cells out in as many columns as possible without
+// exceeding the width, measuring the cells by their display width.
+// It returns false if not even a single column fits.
+func fitIntoWidth(cells []string, across bool, sep string, width int) ([]string, bool) {
+	if len(cells) == 0 {
+		return nil, true
+	}
+	widths := make([]int, len(cells))
+	narrowest := width
+	for i, cell := range cells {
+		widths[i] = cellWidth(cell)
+		if widths[i] < narrowest {
+			narrowest = widths[i]
+		}
+	}
+	sepWidth := cellWidth(sep)
+	// No more columns than the narrowest cells would fill can fit.
+	maxCols := len(cells)
+	if narrowest+sepWidth > 0 && (width+sepWidth)/(narrowest+sepWidth) < maxCols {
+		maxCols = (width + sepWidth) / (narrowest + sepWidth)
+	}
+	for cols := maxCols; cols >= 1; cols-- {
+		rows := (len(cells) + cols - 1) / cols
+		used := cols
+		if !across {
+			// Top to bottom, some column counts give the same rows; they
+			// can only be laid out with the smaller number of columns.
+			used = (len(cells) + rows - 1) / rows
+		}
+		colWidths := make([]int, used)
+		for i, w := range widths {
+			if col := cellColumn(i, used, rows, across); w > colWidths[col] {
+				colWidths[col] = w
+			}
+		}
+		total := sepWidth * (used - 1)
+		for _, w := range colWidths {
+			total += w
+		}
+		if total <= width {
+			return layLines(cells, widths, colWidths, rows, across, sep), true
+		}
+	}
+	return nil, false
+}
+
+func cellColumn(i, cols, rows int, across bool) int {
+	if across {
+		return i % cols
+	}
+	return i / rows
+}
+
+func layLines(cells []string, widths, colWidths []int, rows int, across bool, sep string) []string {
+	cols := len(colWidths)
+	lines := make([]string, rows)
+	for row := range lines {
+		var line strings.Builder
+		for col := 0; col < cols; col++ {
+			i := row*cols + col
+			if !across {
+				i = col*rows + row
+			}
+			if i >= len(cells) {
+				break
+			}
+			if col > 0 {
+				line.WriteString(sep)
+			}
+			line.WriteString(cells[i])
+			next := (row*cols + col + 1) < len(cells)
+			if !across {
+				next = (col+1)*rows+row < len(cells)
+			}
+			if col < cols-1 && next {
+				line.WriteString(strings.Repeat(" ", colWidths[col]-widths[i]))
+			}
+		}
+		lines[row] = line.String()
+	}
+	return lines
+}
@@ -0,0 +1,61 @@
+package ipefmt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCellWidth(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"file.txt", 8},
+		{"日本語", 6},
+		{"ｆｕｌｌ", 8},
+		{"\x1b[01;34mdir\x1b[0m", 3},
+		{"e\u0301", 1},
+		{"👍", 2},
+		{"👍\U0001F3FD", 2},
+		{"👨\u200d👩\u200d👧", 2},
+		{"👨\u200d👩\u200d👧.txt", 6},
+		{"가", 2},
+		{"bad\xff", 4},
+		{"soft\u00adhyphen", 10},
+	}
+	for _, tt := range tests {
+		if got := cellWidth(tt.text); got != tt.want {
+			t.Errorf("cellWidth(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFitIntoWidth(t *testing.T) {
+	cells := []string{"a", "bb", "ccc", "d", "ee"}
+	tests := []struct {
+		name   string
+		cells  []string
+		across bool
+		sep    string
+		width  int
+		want   []string
+		ok     bool
+	}{
+		{"down", cells, false, "  ", 12, []string{"a   ccc  ee", "bb  d"}, true},
+		{"across", cells, true, "  ", 12, []string{"a  bb  ccc", "d  ee"}, true},
+		{"one line", cells, true, " ", 80, []string{"a bb ccc d ee"}, true},
+		{"one column", cells, false, "  ", 3, cells, true},
+		{"wide", []string{"日本", "ab", "c"}, true, " ", 7, []string{"日本 ab", "c"}, true},
+		{"too wide", []string{"日本", "ab", "c"}, true, " ", 6, []string{"日本", "ab", "c"}, true},
+		{"colored", []string{"\x1b[31mab\x1b[0m", "cd"}, true, " ", 5, []string{"\x1b[31mab\x1b[0m cd"}, true},
+		{"no fit", []string{"abcdef"}, false, " ", 3, nil, false},
+		{"empty", nil, false, " ", 3, nil, true},
+	}
+	for _, tt := range tests {
+		got, ok := fitIntoWidth(tt.cells, tt.across, tt.sep, tt.width)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: fitIntoWidth() = %q, %v, want %q, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
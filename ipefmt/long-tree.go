@@ -11,13 +11,14 @@ type longTreeFormatter struct {
 	tree *treeFormatter
 }
 
-func newLongTreeFormatter(args ArgsInfo) *longTreeFormatter {
+func newLongTreeFormatter(args ArgsInfo, repos *gitRepos) *longTreeFormatter {
 	f := &longTreeFormatter{
-		&commonFormatter{args, make([]srcInfo, 0), 0},
-		newLongFormatter(args),
-		newTreeFormatter(args),
+		newCommonFormatter(args, 0, repos),
+		newLongFormatter(args, repos),
+		newTreeFormatter(args, repos),
 	}
 	f.cols = f.long.calculateCols()
+	f.aligns = f.long.calculateAligns()
 	return f
 }
 
@@ -0,0 +1,140 @@
+package ipefmt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/Nhanderu/ipe"
+)
+
+func init() {
+	registerFormat(ArgFormatMarkdown, func(args ArgsInfo, repos *gitRepos) VisitorFormatter {
+		return newMarkdownFormatter(args, repos)
+	})
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`,
+)
+
+// markdownFormatter writes the long view as a Markdown table, and the
+// other views as a tree in a fenced code block or as a nested list.
+// Names are relative to the source, so they can be used as links.
+type markdownFormatter struct {
+	*commonFormatter
+	columns []column
+	src     string
+	body    bytes.Buffer
+	buffer  bytes.Buffer
+}
+
+func newMarkdownFormatter(args ArgsInfo, repos *gitRepos) *markdownFormatter {
+	return &markdownFormatter{commonFormatter: newCommonFormatter(args, 0, repos), columns: columnsToShow(args, repos)}
+}
+
+func (f *markdownFormatter) EnterDir(dir ipe.File, corners []bool) {
+	if len(corners) != 0 {
+		return
+	}
+	f.src = dir.FullName()
+	if f.buffer.Len() > 0 {
+		f.buffer.WriteString("\n")
+	}
+	switch {
+	case f.args.Long:
+		if len(f.args.Sources) > 1 {
+			fmt.Fprintf(&f.buffer, "**%s**\n\n", markdownEscaper.Replace(f.src))
+		}
+		var header, aligns []string
+		for _, col := range f.columns {
+			header = append(header, markdownEscaper.Replace(col.name))
+			if columnAlign(col, f.args) == alignRight {
+				aligns = append(aligns, "---:")
+			} else {
+				aligns = append(aligns, "---")
+			}
+		}
+		f.writeRow(header)
+		f.writeRow(aligns)
+	case f.args.MarkdownStyle == ArgMarkdownList:
+		fmt.Fprintf(&f.buffer, "- %s\n", markdownEscaper.Replace(f.src))
+	default:
+		f.body.Reset()
+		fmt.Fprintf(&f.body, "%s\n", f.src)
+	}
+}
+
+func (f *markdownFormatter) VisitFile(file ipe.File, corners []bool) {
+	switch {
+	case f.args.Long:
+		row := make([]string, len(f.columns))
+		for i, col := range f.columns {
+			if col.value == nil {
+				row[i] = f.link(file, f.relName(file))
+			} else {
+				row[i] = markdownEscaper.Replace(col.value(file, f.args))
+			}
+		}
+		f.writeRow(row)
+	case f.args.MarkdownStyle == ArgMarkdownList:
+		fmt.Fprintf(&f.buffer, "%s- %s\n", strings.Repeat("  ", len(corners)), f.link(file, f.getPlainName(file)))
+	default:
+		fmt.Fprintf(&f.body, "%s%s\n", makeTree(corners), f.getPlainName(file))
+	}
+}
+
+// LeaveDir closes the fenced code block of the source. The fence is longer
+// than any sequence of backticks in the names, so they can't close it.
+func (f *markdownFormatter) LeaveDir(dir ipe.File, corners []bool) {
+	if len(corners) != 0 || f.args.Long || f.args.MarkdownStyle == ArgMarkdownList {
+		return
+	}
+	fence := "```"
+	for strings.Contains(f.body.String(), fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(&f.buffer, "%s\n%s%s\n", fence, f.body.String(), fence)
+}
+
+func (f *markdownFormatter) Error(err error) {
+	if f.buffer.Len() > 0 {
+		f.buffer.WriteString("\n")
+	}
+	fmt.Fprintf(&f.buffer, "**Error:** %s\n", markdownEscaper.Replace(err.Error()))
+}
+
+func (f markdownFormatter) WriteTo(w io.Writer) (int64, error) {
+	return bytes.NewReader(f.buffer.Bytes()).WriteTo(w)
+}
+
+func (f *markdownFormatter) writeRow(cells []string) {
+	fmt.Fprintf(&f.buffer, "| %s |\n", strings.Join(cells, " | "))
+}
+
+// relName returns the path of the file relative to the source, with the
+// type indicator if needed.
+func (f markdownFormatter) relName(file ipe.File) string {
+	name, _ := filepath.Rel(f.src, file.FullName())
+	if f.args.Classify {
+		name += strings.TrimPrefix(file.ClassifiedName(), file.Name())
+	}
+	return name
+}
+
+// link returns the escaped name, linking to the file if needed.
+func (f markdownFormatter) link(file ipe.File, name string) string {
+	if !f.args.MarkdownLinks {
+		return markdownEscaper.Replace(name)
+	}
+	rel, _ := filepath.Rel(f.src, file.FullName())
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return fmt.Sprintf("[%s](%s)", markdownEscaper.Replace(name), strings.Join(segments, "/"))
+}
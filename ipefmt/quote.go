@@ -0,0 +1,144 @@
+package ipefmt
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// shellSpecial are the characters that make a name be quoted in the shell
+// quoting styles.
+const shellSpecial = " \t\n\"#$&'()*;<>?[\\]^`{|}~!="
+
+// quoteName quotes the name in one of the `ArgQuoting*` styles.
+func quoteName(name, style string) string {
+	switch style {
+	case ArgQuotingShell:
+		if !needsShellQuote(name) {
+			return name
+		}
+		return shellQuote(name)
+	case ArgQuotingShellEscape:
+		return shellEscape(name)
+	case ArgQuotingC:
+		return `"` + cEscape(name, false) + `"`
+	case ArgQuotingEscape:
+		return cEscape(name, true)
+	default:
+		return name
+	}
+}
+
+// isUnsafe reports whether the decoded rune is an invalid byte, a C0 or C1
+// control character or a bidirectional override, which can mess with the
+// terminal or hide the real order of the name. Format characters, like the
+// zero width joiner of emoji sequences, and other spaces are safe.
+func isUnsafe(r rune, size int) bool {
+	return (r == utf8.RuneError && size <= 1) || unicode.IsControl(r) ||
+		(r >= '\u202a' && r <= '\u202e') || (r >= '\u2066' && r <= '\u2069')
+}
+
+// hideControlChars replaces the control characters and the invalid bytes
+// of the name with "?", so they can't mess with the terminal.
+func hideControlChars(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); {
+		r, size := utf8.DecodeRuneInString(name[i:])
+		if isUnsafe(r, size) {
+			b.WriteByte('?')
+		} else {
+			b.WriteString(name[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+func needsShellQuote(name string) bool {
+	if name == "" || strings.ContainsAny(name, shellSpecial) {
+		return true
+	}
+	for i := 0; i < len(name); {
+		r, size := utf8.DecodeRuneInString(name[i:])
+		if isUnsafe(r, size) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// shellEscape quotes the name like the shell style, but the control
+// characters are written as `$'...'` sequences, so they can be pasted
+// back into a shell.
+func shellEscape(name string) string {
+	if !needsShellQuote(name) {
+		return name
+	}
+	var b strings.Builder
+	var run strings.Builder
+	flush := func() {
+		if run.Len() > 0 {
+			b.WriteString(shellQuote(run.String()))
+			run.Reset()
+		}
+	}
+	for i := 0; i < len(name); {
+		r, size := utf8.DecodeRuneInString(name[i:])
+		if isUnsafe(r, size) {
+			flush()
+			b.WriteString("$'" + cEscape(name[i:i+size], false) + "'")
+		} else {
+			run.WriteString(name[i : i+size])
+		}
+		i += size
+	}
+	flush()
+	return b.String()
+}
+
+// cEscape escapes the name like a C string literal. The control
+// characters and invalid bytes are written as octal sequences.
+func cEscape(name string, escapeSpace bool) string {
+	var b strings.Builder
+	for i := 0; i < len(name); {
+		r, size := utf8.DecodeRuneInString(name[i:])
+		switch {
+		case r == utf8.RuneError && size <= 1:
+			fmt.Fprintf(&b, "\\%03o", name[i])
+		case r == '\a':
+			b.WriteString(`\a`)
+		case r == '\b':
+			b.WriteString(`\b`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\v':
+			b.WriteString(`\v`)
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '"' && !escapeSpace:
+			b.WriteString(`\"`)
+		case r == ' ' && escapeSpace:
+			b.WriteString(`\ `)
+		case isUnsafe(r, size):
+			for _, c := range []byte(name[i : i+size]) {
+				fmt.Fprintf(&b, "\\%03o", c)
+			}
+		default:
+			b.WriteString(name[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package ipefmt
+
+import "testing"
+
+func TestHideControlChars(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"file.txt", "file.txt"},
+		{"tab\there", "tab?here"},
+		{"new\nline", "new?line"},
+		{"bell\a\x1b[31m", "bell??[31m"},
+		{"c1\u0085", "c1?"},
+		{"invalid\xff\xfe", "invalid??"},
+		{"\u202eevil.exe", "?evil.exe"},
+		{"isolate\u2066x\u2069", "isolate?x?"},
+		{"👨\u200d👩\u200d👧.txt", "👨\u200d👩\u200d👧.txt"},
+		{"no\u00a0break", "no\u00a0break"},
+		{"mark\u200e", "mark\u200e"},
+		{"日本語", "日本語"},
+	}
+	for _, tt := range tests {
+		if got := hideControlChars(tt.name); got != tt.want {
+			t.Errorf("hideControlChars(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteName(t *testing.T) {
+	tests := []struct {
+		name  string
+		style string
+		want  string
+	}{
+		{"plain", ArgQuotingLiteral, "plain"},
+		{"with space", ArgQuotingLiteral, "with space"},
+		{"plain", ArgQuotingShell, "plain"},
+		{"with space", ArgQuotingShell, "'with space'"},
+		{"it's", ArgQuotingShell, `'it'\''s'`},
+		{"", ArgQuotingShell, "''"},
+		{"👨\u200d👩\u200d👧", ArgQuotingShell, "👨\u200d👩\u200d👧"},
+		{"new\nline", ArgQuotingShell, "'new\nline'"},
+		{"plain", ArgQuotingShellEscape, "plain"},
+		{"new\nline", ArgQuotingShellEscape, `'new'$'\n''line'`},
+		{"bad\xff", ArgQuotingShellEscape, `'bad'$'\377'`},
+		{"a b", ArgQuotingShellEscape, "'a b'"},
+		{`say "hi"`, ArgQuotingC, `"say \"hi\""`},
+		{"tab\t\x01", ArgQuotingC, `"tab\t\001"`},
+		{"no\u00a0break", ArgQuotingC, "\"no\u00a0break\""},
+		{"a b\\c", ArgQuotingEscape, `a\ b\\c`},
+		{"\u202e", ArgQuotingEscape, `\342\200\256`},
+	}
+	for _, tt := range tests {
+		if got := quoteName(tt.name, tt.style); got != tt.want {
+			t.Errorf("quoteName(%q, %q) = %q, want %q", tt.name, tt.style, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package ipefmt
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Nhanderu/ipe"
+)
+
+const blockSize = 512
+
+var (
+	iecUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	siUnits  = []string{"B", "kB", "MB", "GB", "TB", "PB", "EB"}
+)
+
+// SizeFormat represents the options for formatting a file size.
+type SizeFormat struct {
+	// Style is one of the `ArgSize*` constants. If it's empty, `ArgSizeIEC`
+	// is used.
+	Style string
+	// Decimals is the number of decimal places in the human-readable styles.
+	Decimals uint8
+	// Thousands is the separator of the thousands groups in the raw styles.
+	Thousands string
+}
+
+// FormatSize formats the size, in bytes, with the given options.
+// In the `ArgSizeBlocks` style, the size is shown as the number of
+// 512-byte blocks needed to store it.
+func FormatSize(size int64, format SizeFormat) string {
+	switch format.Style {
+	case ArgSizeBytes:
+		return groupThousands(size, format.Thousands)
+	case ArgSizeBlocks:
+		return groupThousands((size+blockSize-1)/blockSize, format.Thousands)
+	case ArgSizeSI:
+		return humanSize(size, 1000, siUnits, format.Decimals)
+	default:
+		return humanSize(size, 1024, iecUnits, format.Decimals)
+	}
+}
+
+// fmtSize formats the size of the file, based on the arguments.
+func fmtSize(f ipe.File, args ArgsInfo) string {
+	if f.IsDir() {
+		return "-"
+	}
+	return FormatSize(f.Size(), args.sizeFormat())
+}
+
+func humanSize(size int64, base float64, units []string, decimals uint8) string {
+	if size < int64(base) && size > -int64(base) {
+		return strconv.FormatInt(size, 10) + units[0]
+	}
+	value := float64(size)
+	unit := 0
+	for (value >= base || value <= -base) && unit < len(units)-1 {
+		value /= base
+		unit++
+	}
+	// Rounding may carry the value into the next unit (e.g. 1023.96KiB).
+	s := strconv.FormatFloat(value, 'f', int(decimals), 64)
+	if r, _ := strconv.ParseFloat(s, 64); (r >= base || r <= -base) && unit < len(units)-1 {
+		value /= base
+		unit++
+		s = strconv.FormatFloat(value, 'f', int(decimals), 64)
+	}
+	return s + units[unit]
+}
+
+func groupThousands(n int64, sep string) string {
+	s := strconv.FormatInt(n, 10)
+	if sep == "" {
+		return s
+	}
+	var sign string
+	if n < 0 {
+		sign, s = "-", s[1:]
+	}
+	if len(s) <= 3 {
+		return sign + s
+	}
+	var b strings.Builder
+	b.WriteString(sign)
+	head := len(s) % 3
+	if head > 0 {
+		b.WriteString(s[:head])
+	}
+	for i := head; i < len(s); i += 3 {
+		if i > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(s[i : i+3])
+	}
+	return b.String()
+}
@@ -0,0 +1,60 @@
+package ipefmt
+
+import "testing"
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size   int64
+		format SizeFormat
+		want   string
+	}{
+		{0, SizeFormat{}, "0B"},
+		{1023, SizeFormat{}, "1023B"},
+		{1024, SizeFormat{}, "1KiB"},
+		{1536, SizeFormat{Decimals: 1}, "1.5KiB"},
+		{1536, SizeFormat{}, "2KiB"},
+		{1048535, SizeFormat{Decimals: 1}, "1.0MiB"},
+		{1048575, SizeFormat{}, "1MiB"},
+		{-2048, SizeFormat{Style: ArgSizeIEC}, "-2KiB"},
+		{1 << 62, SizeFormat{}, "4EiB"},
+		{999, SizeFormat{Style: ArgSizeSI}, "999B"},
+		{1000, SizeFormat{Style: ArgSizeSI}, "1kB"},
+		{1234567, SizeFormat{Style: ArgSizeSI, Decimals: 2}, "1.23MB"},
+		{999999, SizeFormat{Style: ArgSizeSI, Decimals: 1}, "1.0MB"},
+		{1234567, SizeFormat{Style: ArgSizeBytes}, "1234567"},
+		{1234567, SizeFormat{Style: ArgSizeBytes, Thousands: ","}, "1,234,567"},
+		{-1234, SizeFormat{Style: ArgSizeBytes, Thousands: "."}, "-1.234"},
+		{0, SizeFormat{Style: ArgSizeBlocks}, "0"},
+		{1, SizeFormat{Style: ArgSizeBlocks}, "1"},
+		{512, SizeFormat{Style: ArgSizeBlocks}, "1"},
+		{513, SizeFormat{Style: ArgSizeBlocks}, "2"},
+		{512 * 1000, SizeFormat{Style: ArgSizeBlocks, Thousands: " "}, "1 000"},
+	}
+	for _, tt := range tests {
+		if got := FormatSize(tt.size, tt.format); got != tt.want {
+			t.Errorf("FormatSize(%d, %+v) = %q, want %q", tt.size, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestGroupThousands(t *testing.T) {
+	tests := []struct {
+		n    int64
+		sep  string
+		want string
+	}{
+		{0, ",", "0"},
+		{999, ",", "999"},
+		{1000, ",", "1,000"},
+		{100000, ",", "100,000"},
+		{1000000, "_", "1_000_000"},
+		{-999, ",", "-999"},
+		{-1000, ",", "-1,000"},
+		{1000000, "", "1000000"},
+	}
+	for _, tt := range tests {
+		if got := groupThousands(tt.n, tt.sep); got != tt.want {
+			t.Errorf("groupThousands(%d, %q) = %q, want %q", tt.n, tt.sep, got, tt.want)
+		}
+	}
+}
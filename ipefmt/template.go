@@ -0,0 +1,115 @@
+package ipefmt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"github.com/Nhanderu/ipe"
+)
+
+// TemplateData represents the data the template is executed with, for
+// each entry. The methods of the file can be used directly, like
+// `{{.Name}}` or `{{.User.Username}}`.
+type TemplateData struct {
+	ipe.File
+	// Path is the path of the entry relative to its source.
+	Path string
+	// Depth is the depth of the entry, starting at 1 for the entries
+	// directly inside the source.
+	Depth int
+}
+
+// ParseTemplate parses the template that formats each entry.
+// Besides the built-in functions, it can use:
+//
+//	size SIZE                 formats the size, based on the arguments
+//	sizeStyle STYLE SIZE      formats the size in one of the size styles
+//	time TIME                 formats the timestamp, based on the arguments
+//	timeStyle STYLE TIME      formats the timestamp in one of the time styles
+//	mode MODE                 formats the mode bits, like "-rw-r--r--"
+//	octal MODE                formats the permission bits, like "0644"
+//	filetype FILE             returns the type of the file, like "directory"
+//	classify FILE             returns the name with its type indicator
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("entry").Funcs(templateFuncs(ArgsInfo{})).Parse(text)
+}
+
+func templateFuncs(args ArgsInfo) template.FuncMap {
+	return template.FuncMap{
+		"size": func(size int64) string {
+			return FormatSize(size, args.sizeFormat())
+		},
+		"sizeStyle": func(style string, size int64) string {
+			format := args.sizeFormat()
+			format.Style = style
+			return FormatSize(size, format)
+		},
+		"time": func(t time.Time) string {
+			return fmtTime(t, args)
+		},
+		"timeStyle": func(style string, t time.Time) string {
+			format := args.timeFormat()
+			format.Style = style
+			return FormatTime(t, format)
+		},
+		"mode": func(mode os.FileMode) string {
+			return mode.String()
+		},
+		"octal": func(mode os.FileMode) string {
+			return fmt.Sprintf("%04o", mode.Perm())
+		},
+		"filetype": fileType,
+		"classify": func(file ipe.File) string {
+			return file.ClassifiedName()
+		},
+	}
+}
+
+// templateFormatter writes every entry with the template.
+type templateFormatter struct {
+	args   ArgsInfo
+	tmpl   *template.Template
+	src    string
+	buffer bytes.Buffer
+	err    error
+}
+
+func newTemplateFormatter(args ArgsInfo) *templateFormatter {
+	// The functions are bound again, now with the arguments.
+	tmpl := template.Must(args.Template.Clone()).Funcs(templateFuncs(args))
+	return &templateFormatter{args: args, tmpl: tmpl}
+}
+
+func (f *templateFormatter) EnterDir(dir ipe.File, corners []bool) {
+	if len(corners) == 0 {
+		f.src = dir.FullName()
+	}
+}
+
+func (f *templateFormatter) VisitFile(file ipe.File, corners []bool) {
+	path, _ := filepath.Rel(f.src, file.FullName())
+	err := f.tmpl.Execute(&f.buffer, TemplateData{file, path, len(corners)})
+	f.Error(err)
+}
+
+func (f *templateFormatter) LeaveDir(dir ipe.File, corners []bool) {}
+
+// Error keeps the first error, so it's returned by `WriteTo`.
+func (f *templateFormatter) Error(err error) {
+	if f.err == nil {
+		f.err = err
+	}
+}
+
+func (f *templateFormatter) WriteTo(w io.Writer) (int64, error) {
+	n, err := bytes.NewReader(f.buffer.Bytes()).WriteTo(w)
+	if err != nil {
+		return n, err
+	}
+	return n, f.err
+}
@@ -0,0 +1,319 @@
+package ipefmt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/user"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Nhanderu/ipe"
+	"github.com/Nhanderu/ipe/git"
+)
+
+// Theme represents the colors of the long view columns, by style.
+// The colors are SGR parameters, like "01;34". Styles without a color
+// aren't colored.
+//
+// The styles are:
+//
+//	header                     the header row
+//	inode, links, blocks       the columns of the same name
+//	perm-type                  the file type and special mode letters
+//	perm-read, perm-write      the permission bits that are set
+//	perm-exec
+//	perm-special               the setuid, setgid and sticky letters
+//	perm-none                  the permission bits that aren't set
+//	size-bytes, size-kilo      the sizes, by magnitude (B, KiB, MiB, GiB,
+//	size-mega, size-giga       and bigger)
+//	size-huge
+//	size-none                  the size of directories
+//	user-you, user-other       the user, if it's the current user or not
+//	group-yours, group-other   the group, if the current user is in it or not
+//	time-hour, time-day        the timestamps, by age (less than an hour,
+//	time-week, time-month      a day, a week, a month or older)
+//	time-old
+//	git-new                    the Git status letters: new or untracked files,
+//	git-modified               modified, deleted, with the type changed,
+//	git-deleted                conflicted, ignored and unmodified
+//	git-typechange
+//	git-conflicted
+//	git-ignored
+//	git-unmodified
+//	commit-hash, commit-author the hash and the author of the last commit;
+//	                           its date uses the time styles
+//	ignored                    the names of the files ignored by Git
+type Theme map[string]string
+
+var themeStyles = []string{
+	"header", "inode", "links", "blocks",
+	"perm-type", "perm-read", "perm-write", "perm-exec", "perm-special", "perm-none",
+	"size-bytes", "size-kilo", "size-mega", "size-giga", "size-huge", "size-none",
+	"user-you", "user-other", "group-yours", "group-other",
+	"time-hour", "time-day", "time-week", "time-month", "time-old",
+	"git-new", "git-modified", "git-deleted", "git-typechange",
+	"git-conflicted", "git-ignored", "git-unmodified",
+	"commit-hash", "commit-author", "ignored",
+}
+
+var themes = map[string]Theme{
+	ArgThemeDefault: {
+		"header":         "04",
+		"inode":          "35",
+		"links":          "01;31",
+		"blocks":         "36",
+		"perm-type":      "01;34",
+		"perm-read":      "01;33",
+		"perm-write":     "01;31",
+		"perm-exec":      "01;32",
+		"perm-special":   "01;35",
+		"perm-none":      "90",
+		"size-bytes":     "32",
+		"size-kilo":      "01;32",
+		"size-mega":      "01;33",
+		"size-giga":      "01;31",
+		"size-huge":      "01;35",
+		"size-none":      "90",
+		"user-you":       "01;33",
+		"group-yours":    "33",
+		"time-hour":      "01;34",
+		"time-day":       "34",
+		"time-week":      "36",
+		"time-month":     "37",
+		"time-old":       "90",
+		"git-new":        "32",
+		"git-modified":   "34",
+		"git-deleted":    "31",
+		"git-typechange": "35",
+		"git-conflicted": "01;31",
+		"git-ignored":    "90",
+		"git-unmodified": "90",
+		"commit-hash":    "33",
+		"commit-author":  "36",
+		"ignored":        "02",
+	},
+	ArgThemeMono: {
+		"header":         "04",
+		"perm-type":      "01",
+		"perm-write":     "01",
+		"perm-none":      "02",
+		"size-mega":      "01",
+		"size-giga":      "01",
+		"size-huge":      "01;04",
+		"size-none":      "02",
+		"user-you":       "01",
+		"time-hour":      "01",
+		"time-day":       "01",
+		"time-old":       "02",
+		"git-new":        "01",
+		"git-modified":   "01",
+		"git-conflicted": "01;04",
+		"git-ignored":    "02",
+		"git-unmodified": "02",
+		"commit-hash":    "02",
+		"ignored":        "02",
+	},
+	ArgThemeNone: {},
+}
+
+// Themes returns the names of the built-in themes, sorted.
+func Themes() []string {
+	var names []string
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BuiltinTheme returns the built-in theme with the name.
+func BuiltinTheme(name string) (Theme, bool) {
+	theme, ok := themes[name]
+	return theme, ok
+}
+
+// ParseTheme parses a theme file. Each line has a style and its color,
+// separated by spaces or "=", like "size-giga 01;31". Lines starting with
+// "#" are comments. Styles not in the file aren't colored.
+func ParseTheme(r io.Reader) (Theme, error) {
+	theme := make(Theme)
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(strings.Replace(line, "=", " ", 1))
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a style and a color", n)
+		}
+		if !isThemeStyle(fields[0]) {
+			return nil, fmt.Errorf("line %d: unknown style %q", n, fields[0])
+		}
+		theme[fields[0]] = fields[1]
+	}
+	return theme, scanner.Err()
+}
+
+func isThemeStyle(style string) bool {
+	for _, s := range themeStyles {
+		if s == style {
+			return true
+		}
+	}
+	return false
+}
+
+// painter colors the cells of the long view with a theme. With `html`,
+// the cells are escaped HTML, with the styles as the classes of spans.
+type painter struct {
+	theme Theme
+	color bool
+	html  bool
+	base  int64
+	uid   string
+	gids  map[string]bool
+	now   time.Time
+}
+
+func newPainter(args ArgsInfo) painter {
+	p := painter{
+		theme: args.Theme,
+		color: args.Color != ArgColorNever,
+		base:  1024,
+		gids:  make(map[string]bool),
+		now:   time.Now(),
+	}
+	if p.theme == nil {
+		p.theme = themes[ArgThemeDefault]
+	}
+	if args.sizeFormat().Style == ArgSizeSI {
+		p.base = 1000
+	}
+	if u, err := user.Current(); err == nil {
+		p.uid = u.Uid
+		gids, _ := u.GroupIds()
+		for _, gid := range gids {
+			p.gids[gid] = true
+		}
+	}
+	return p
+}
+
+// paint colors the text with the color of the style.
+func (p painter) paint(style, text string) string {
+	if p.html {
+		return htmlSpan(style, text)
+	}
+	sgr := p.theme[style]
+	if !p.color || sgr == "" || sgr == "0" || sgr == "00" {
+		return text
+	}
+	return newSGRColor(sgr, true).Sprint(text)
+}
+
+// paintMode colors each letter of the mode string. The permission bits
+// are always the last nine letters.
+func (p painter) paintMode(mode string) string {
+	if len(mode) < 9 {
+		return mode
+	}
+	var b strings.Builder
+	prefix := len(mode) - 9
+	for i, c := range mode {
+		letter := string(c)
+		switch {
+		case i < prefix && strings.ContainsRune("ugt", c):
+			b.WriteString(p.paint("perm-special", letter))
+		case i < prefix && c != '-':
+			b.WriteString(p.paint("perm-type", letter))
+		case c == 'r':
+			b.WriteString(p.paint("perm-read", letter))
+		case c == 'w':
+			b.WriteString(p.paint("perm-write", letter))
+		case c == 'x':
+			b.WriteString(p.paint("perm-exec", letter))
+		default:
+			b.WriteString(p.paint("perm-none", letter))
+		}
+	}
+	return b.String()
+}
+
+// paintSize colors the size by its unit, in the base of the size style.
+func (p painter) paintSize(file ipe.File, size string) string {
+	s, kilo := file.Size(), p.base
+	switch {
+	case file.IsDir():
+		return p.paint("size-none", size)
+	case s < kilo:
+		return p.paint("size-bytes", size)
+	case s < kilo*kilo:
+		return p.paint("size-kilo", size)
+	case s < kilo*kilo*kilo:
+		return p.paint("size-mega", size)
+	case s < kilo*kilo*kilo*kilo:
+		return p.paint("size-giga", size)
+	default:
+		return p.paint("size-huge", size)
+	}
+}
+
+func (p painter) paintUser(file ipe.File, name string) string {
+	if u := file.User(); u != nil && u.Uid == p.uid {
+		return p.paint("user-you", name)
+	}
+	return p.paint("user-other", name)
+}
+
+func (p painter) paintGroup(file ipe.File, name string) string {
+	if g := file.Group(); g != nil && p.gids[g.Gid] {
+		return p.paint("group-yours", name)
+	}
+	return p.paint("group-other", name)
+}
+
+func (p painter) paintTime(t time.Time, text string) string {
+	age := p.now.Sub(t)
+	switch {
+	case age < time.Hour:
+		return p.paint("time-hour", text)
+	case age < 24*time.Hour:
+		return p.paint("time-day", text)
+	case age < 7*24*time.Hour:
+		return p.paint("time-week", text)
+	case age < 30*24*time.Hour:
+		return p.paint("time-month", text)
+	default:
+		return p.paint("time-old", text)
+	}
+}
+
+// gitStyles are the styles of the letters of the Git status.
+var gitStyles = map[rune]string{
+	'A': "git-new",
+	'?': "git-new",
+	'M': "git-modified",
+	'D': "git-deleted",
+	'T': "git-typechange",
+	'U': "git-conflicted",
+	'!': "git-ignored",
+	'-': "git-unmodified",
+}
+
+// paintGit colors each letter of the Git status.
+func (p painter) paintGit(status string) string {
+	var b strings.Builder
+	for _, c := range status {
+		b.WriteString(p.paint(gitStyles[c], string(c)))
+	}
+	return b.String()
+}
+
+// paintCommit colors the date, the hash and the author of the last commit,
+// with the date already formatted.
+func (p painter) paintCommit(c *git.Commit, date string) string {
+	return p.paintTime(c.Time, date) + " " + p.paint("commit-hash", shortHash(c.Hash)) + " " + p.paint("commit-author", c.Author)
+}
@@ -0,0 +1,125 @@
+package ipefmt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeFormat represents the options for formatting a timestamp.
+type TimeFormat struct {
+	// Style is one of the `ArgTimeStyle*` constants or a custom Go layout
+	// prefixed with "+", like "+2006-01-02". If it's empty or unknown,
+	// `ArgTimeStyleDefault` is used.
+	Style string
+	// Location is the time zone the timestamp is shown in.
+	// If it's nil, the local time zone is used.
+	Location *time.Location
+}
+
+// FormatTime formats the timestamp with the given options.
+func FormatTime(t time.Time, format TimeFormat) string {
+	now := time.Now()
+	if format.Location != nil {
+		t, now = t.In(format.Location), now.In(format.Location)
+	} else {
+		t, now = t.Local(), now.Local()
+	}
+	if strings.HasPrefix(format.Style, "+") {
+		return t.Format(format.Style[1:])
+	}
+	switch format.Style {
+	case ArgTimeStyleISO:
+		if isRecent(t, now) {
+			return t.Format("01-02 15:04")
+		}
+		return t.Format("2006-01-02 ")
+	case ArgTimeStyleLongISO:
+		return t.Format("2006-01-02 15:04")
+	case ArgTimeStyleFullISO:
+		return t.Format("2006-01-02 15:04:05.000000000 -0700")
+	case ArgTimeStyleRelative:
+		return fmtRelative(now.Sub(t))
+	case ArgTimeStyleUnix:
+		return strconv.FormatInt(t.Unix(), 10)
+	default:
+		year, month, day := t.Date()
+		str := fmt.Sprintf("%2d %s ", day, month.String()[:3])
+		if year == now.Year() {
+			return fmt.Sprintf("%s%2d:%02d", str, t.Hour(), t.Minute())
+		}
+		return fmt.Sprintf("%s%d ", str, year)
+	}
+}
+
+// ParseTimeStyle validates the style of the timestamps: one of the
+// `ArgTimeStyle*` constants or a custom Go layout prefixed with "+".
+func ParseTimeStyle(style string) (string, error) {
+	switch style {
+	case ArgTimeStyleDefault, ArgTimeStyleISO, ArgTimeStyleLongISO,
+		ArgTimeStyleFullISO, ArgTimeStyleRelative, ArgTimeStyleUnix:
+		return style, nil
+	}
+	if strings.HasPrefix(style, "+") {
+		return style, nil
+	}
+	return "", fmt.Errorf("unknown time style %q; custom layouts start with \"+\"", style)
+}
+
+// fmtTime formats the timestamp, based on the arguments.
+func fmtTime(t time.Time, args ArgsInfo) string {
+	return FormatTime(t, args.timeFormat())
+}
+
+// fmtRawTime formats the timestamp in RFC 3339, in the time zone given
+// by the arguments.
+func fmtRawTime(t time.Time, args ArgsInfo) string {
+	if args.Location != nil {
+		return t.In(args.Location).Format(time.RFC3339)
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+// isRecent reports whether the timestamp is within the last six months,
+// the same rule `ls` uses to choose between showing the time or the year.
+func isRecent(t, now time.Time) bool {
+	const sixMonths = 183 * 24 * time.Hour
+	return now.Sub(t) < sixMonths && t.Sub(now) < time.Hour
+}
+
+func fmtRelative(d time.Duration) string {
+	future := d < 0
+	if future {
+		d = -d
+	}
+	if d < time.Minute {
+		return "just now"
+	}
+	units := []struct {
+		size time.Duration
+		name string
+	}{
+		{365 * 24 * time.Hour, "year"},
+		{30 * 24 * time.Hour, "month"},
+		{7 * 24 * time.Hour, "week"},
+		{24 * time.Hour, "day"},
+		{time.Hour, "hour"},
+		{time.Minute, "minute"},
+	}
+	for _, u := range units {
+		if d < u.size {
+			continue
+		}
+		n := int64(d / u.size)
+		str := fmt.Sprintf("%d %s", n, u.name)
+		if n != 1 {
+			str += "s"
+		}
+		if future {
+			return "in " + str
+		}
+		return str + " ago"
+	}
+	return "just now"
+}
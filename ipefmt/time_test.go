@@ -0,0 +1,88 @@
+package ipefmt
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestFormatTime(t *testing.T) {
+	loc := time.FixedZone("BRT", -3*60*60)
+	old := time.Date(2001, time.February, 3, 4, 5, 6, 7, time.UTC)
+	now := time.Now().In(loc)
+	recent := now.Add(-time.Hour)
+	tests := []struct {
+		t     time.Time
+		style string
+		want  string
+	}{
+		{old, ArgTimeStyleDefault, " 3 Feb 2001 "},
+		{now, ArgTimeStyleDefault, now.Format("_2 Jan ") + fmt.Sprintf("%2d:%02d", now.Hour(), now.Minute())},
+		{old, ArgTimeStyleISO, "2001-02-03 "},
+		{recent, ArgTimeStyleISO, recent.Format("01-02 15:04")},
+		{old, ArgTimeStyleLongISO, "2001-02-03 01:05"},
+		{old, ArgTimeStyleFullISO, "2001-02-03 01:05:06.000000007 -0300"},
+		{old, ArgTimeStyleUnix, "981173106"},
+		{recent, ArgTimeStyleRelative, "1 hour ago"},
+		{old, "+2006/01/02 15h", "2001/02/03 01h"},
+		{old, "+", ""},
+	}
+	for _, tt := range tests {
+		got := FormatTime(tt.t, TimeFormat{Style: tt.style, Location: loc})
+		if got != tt.want {
+			t.Errorf("FormatTime(%v, %q) = %q, want %q", tt.t, tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestParseTimeStyle(t *testing.T) {
+	tests := []struct {
+		style string
+		ok    bool
+	}{
+		{ArgTimeStyleDefault, true},
+		{ArgTimeStyleISO, true},
+		{ArgTimeStyleLongISO, true},
+		{ArgTimeStyleFullISO, true},
+		{ArgTimeStyleRelative, true},
+		{ArgTimeStyleUnix, true},
+		{"+2006", true},
+		{"2006-01-02", false},
+		{"iso8601", false},
+	}
+	for _, tt := range tests {
+		style, err := ParseTimeStyle(tt.style)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseTimeStyle(%q) error = %v, want ok %v", tt.style, err, tt.ok)
+		}
+		if err == nil && style != tt.style {
+			t.Errorf("ParseTimeStyle(%q) = %q", tt.style, style)
+		}
+	}
+}
+
+func TestFmtRelative(t *testing.T) {
+	const day = 24 * time.Hour
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "just now"},
+		{59 * time.Second, "just now"},
+		{-30 * time.Second, "just now"},
+		{time.Minute, "1 minute ago"},
+		{90 * time.Minute, "1 hour ago"},
+		{2 * time.Hour, "2 hours ago"},
+		{-3 * time.Hour, "in 3 hours"},
+		{day, "1 day ago"},
+		{13 * day, "1 week ago"},
+		{45 * day, "1 month ago"},
+		{400 * day, "1 year ago"},
+		{-800 * day, "in 2 years"},
+	}
+	for _, tt := range tests {
+		if got := fmtRelative(tt.d); got != tt.want {
+			t.Errorf("fmtRelative(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -9,16 +9,16 @@ type treeFormatter struct {
 	*commonFormatter
 }
 
-func newTreeFormatter(args ArgsInfo) *treeFormatter {
-	return &treeFormatter{&commonFormatter{args, make([]srcInfo, 0), 1}}
+func newTreeFormatter(args ArgsInfo, repos *gitRepos) *treeFormatter {
+	return &treeFormatter{newCommonFormatter(args, 1, repos)}
 }
 
 func (f *treeFormatter) getDir(file ipe.File, grid **gridt.Grid, corners []bool) {
 	if len(corners) == 0 {
-		f.appendSource(srcInfo{file, nil, *grid})
+		f.appendSource(srcInfo{file, nil, *grid, false})
 	}
 }
 
 func (f *treeFormatter) getFile(file ipe.File, grid *gridt.Grid, corners []bool) {
-	grid.Add(makeTree(corners) + f.getName(file))
+	grid.Add(makeTree(corners) + f.gitMarker(file) + f.getName(file))
 }
@@ -4,56 +4,73 @@ import (
 	"fmt"
 	"os"
 	"runtime"
-	"strconv"
 	"strings"
-	"time"
 
 	"github.com/Nhanderu/ipe"
 )
 
-const (
-	kilobyte = 1024
-	megabyte = kilobyte * 1024
-	gigabyte = megabyte * 1024
-	terabyte = gigabyte * 1024
+const osWindows = runtime.GOOS == "windows"
 
-	osWindows = runtime.GOOS == "windows"
-)
-
-func fmtSize(f ipe.File) string {
+func fmtBlocks(f ipe.File, args ArgsInfo) string {
 	if f.IsDir() {
 		return "-"
 	}
-	s := f.Size()
-	if s < kilobyte {
-		return fmt.Sprintf("%dB", s)
-	}
-	if s < megabyte {
-		return fmt.Sprintf("%.1dKB", s/kilobyte)
-	}
-	if s < gigabyte {
-		return fmt.Sprintf("%.1dMB", s/megabyte)
-	}
-	if s < terabyte {
-		return fmt.Sprintf("%.1dGB", s/gigabyte)
-	}
-	return fmt.Sprintf("%.1dTB", s/terabyte)
+	return groupThousands(f.Blocks(), args.Thousands)
 }
 
-func fmtBlocks(f ipe.File) string {
-	if f.IsDir() {
-		return "-"
+// fileType returns the name of the type of the file.
+func fileType(f ipe.File) string {
+	switch {
+	case f.IsDir():
+		return "directory"
+	case f.IsSymlink():
+		return "link"
+	case f.IsNamedPipe():
+		return "fifo"
+	case f.IsSocket():
+		return "socket"
+	case f.IsDevice():
+		return "device"
+	default:
+		return "file"
 	}
-	return strconv.FormatInt(f.Blocks(), 10)
 }
 
-func fmtTime(t time.Time) string {
-	year, month, day := t.Date()
-	str := fmt.Sprintf("%2d %s ", day, month.String()[:3])
-	if year == time.Now().Year() {
-		return fmt.Sprintf("%s%2d:%02d", str, t.Hour(), t.Minute())
+// fileClass returns the class of the file, used to choose its color.
+// The classes are the keys of `LS_COLORS`.
+func fileClass(f ipe.File) string {
+	mode := f.Mode()
+	switch {
+	case f.IsDir() && mode&os.ModeSticky != 0 && mode&0002 != 0:
+		return "tw"
+	case f.IsDir() && mode&0002 != 0:
+		return "ow"
+	case f.IsDir() && mode&os.ModeSticky != 0:
+		return "st"
+	case f.IsDir():
+		return "di"
+	case f.IsSymlink():
+		if _, err := os.Stat(f.FullName()); err != nil {
+			return "or"
+		}
+		return "ln"
+	case f.IsNamedPipe():
+		return "pi"
+	case f.IsSocket():
+		return "so"
+	case f.IsDevice() && mode&os.ModeCharDevice != 0:
+		return "cd"
+	case f.IsDevice():
+		return "bd"
+	case mode&os.ModeSetuid != 0:
+		return "su"
+	case mode&os.ModeSetgid != 0:
+		return "sg"
+	case mode&0111 != 0:
+		return "ex"
+	default:
+		return "fi"
 	}
-	return fmt.Sprintf("%s%d ", str, year)
 }
 
 func fixInSrc(src string) string {
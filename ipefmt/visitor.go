@@ -0,0 +1,141 @@
+package ipefmt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/Nhanderu/gridt"
+	"github.com/Nhanderu/ipe"
+)
+
+var (
+	// ErrFormatExists is returned when registering a format with the same
+	// name as an already registered one.
+	ErrFormatExists = errors.New("there is already a format with this name")
+	// ErrFormatName is returned when registering a format without name.
+	ErrFormatName = errors.New("the format has no name")
+)
+
+// Visitor receives the entries of the sources while they're traversed.
+// The entries are already filtered, sorted and dereferenced, based on the
+// arguments.
+//
+// The `corners` slice describes the position of the entry in the tree:
+// its length is the depth of the entry and each value reports whether the
+// entry (or its ancestor, in that level) is the last one of its directory.
+// It's empty for the sources and must not be retained after the call.
+type Visitor interface {
+	// EnterDir is called before the entries of a directory are visited,
+	// for the sources and, when recursing, for the directories under them.
+	// Empty directories are entered too, and left right away.
+	EnterDir(dir ipe.File, corners []bool)
+	// VisitFile is called for every entry.
+	VisitFile(file ipe.File, corners []bool)
+	// LeaveDir is called after all the entries of a directory are visited,
+	// once for every call to EnterDir.
+	LeaveDir(dir ipe.File, corners []bool)
+	// Error is called when a source can't be read.
+	Error(err error)
+}
+
+// VisitorFormatter represents a custom output format. It's fed by the
+// traversal and then written out.
+type VisitorFormatter interface {
+	Visitor
+	io.WriterTo
+}
+
+// FormatConstructor creates a new formatter for the arguments.
+type FormatConstructor func(args ArgsInfo) VisitorFormatter
+
+// formatConstructor is how the formats are kept. The built-in ones also
+// get the repositories of the output, to show what they say about the
+// files.
+type formatConstructor func(args ArgsInfo, repos *gitRepos) VisitorFormatter
+
+var (
+	formats      = make(map[string]formatConstructor)
+	formatsMutex sync.RWMutex
+)
+
+// RegisterFormat registers a custom output format, making it available to
+// `NewFormatter` through the `Format` argument.
+func RegisterFormat(name string, constructor FormatConstructor) error {
+	return registerFormat(name, func(args ArgsInfo, repos *gitRepos) VisitorFormatter {
+		return constructor(args)
+	})
+}
+
+func registerFormat(name string, constructor formatConstructor) error {
+	if name == "" {
+		return ErrFormatName
+	}
+	formatsMutex.Lock()
+	defer formatsMutex.Unlock()
+	if _, ok := formats[name]; ok || name == ArgFormatText {
+		return ErrFormatExists
+	}
+	formats[name] = constructor
+	return nil
+}
+
+// Formats returns the names of all the available formats, sorted.
+func Formats() []string {
+	formatsMutex.RLock()
+	defer formatsMutex.RUnlock()
+	names := []string{ArgFormatText}
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func findFormat(name string) (formatConstructor, bool) {
+	formatsMutex.RLock()
+	defer formatsMutex.RUnlock()
+	constructor, ok := formats[name]
+	return constructor, ok
+}
+
+// visitorFormatter adapts a `VisitorFormatter` to the traversal.
+type visitorFormatter struct {
+	visitor VisitorFormatter
+}
+
+func newVisitorFormatter(visitor VisitorFormatter) *visitorFormatter {
+	return &visitorFormatter{visitor}
+}
+
+// String outputs the formatter into a correct string.
+func (f visitorFormatter) String() string {
+	var buffer bytes.Buffer
+	f.WriteTo(&buffer)
+	return buffer.String()
+}
+
+// WriteTo writes the values of the formatter into a writer.
+func (f visitorFormatter) WriteTo(w io.Writer) (int64, error) {
+	return f.visitor.WriteTo(w)
+}
+
+func (f *visitorFormatter) getDir(file ipe.File, grid **gridt.Grid, corners []bool) {
+	f.visitor.EnterDir(file, corners)
+}
+
+func (f *visitorFormatter) getFile(file ipe.File, grid *gridt.Grid, corners []bool) {
+	f.visitor.VisitFile(file, corners)
+}
+
+func (f *visitorFormatter) leaveDir(file ipe.File, corners []bool) {
+	f.visitor.LeaveDir(file, corners)
+}
+
+func (f *visitorFormatter) appendSource(src srcInfo) {
+	if src.err != nil {
+		f.visitor.Error(src.err)
+	}
+}
@@ -1,53 +1,58 @@
 package ipefmt
 
 import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/Nhanderu/gridt"
 	"github.com/Nhanderu/ipe"
-	"github.com/fatih/color"
+	"github.com/Nhanderu/ipe/git"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
+// formatterWrapper reads the sources and walks through them, leaving the
+// output to the specific formatter. The sources are read when writing, so
+// the automatic options can be decided for the writer.
 type formatterWrapper struct {
 	Formatter
-	args ArgsInfo
+	args         ArgsInfo
+	newFormatter func(args ArgsInfo, repos *gitRepos) Formatter
+	repos        *gitRepos
+	root         string
+	ignoreFiles  *git.Matcher
 }
 
+// ignoreFileNames are the names of the files with gitignore syntax whose
+// patterns hide entries of their directory and of the ones below it.
+var ignoreFileNames = []string{".ipeignore", ".ignore"}
+
 func (f *formatterWrapper) getDir(file ipe.File, grid **gridt.Grid, corners []bool) {
-	// Gets all the files inside the directory.
+	// Gets all the files inside the directory. Empty directories still
+	// reach the formatter, so they're listed too.
 	fs := file.Children()
-	if fs == nil || len(fs) == 0 {
+	if len(fs) == 0 && !file.IsDir() {
 		return
 	}
 	f.Formatter.getDir(file, grid, corners)
+	if len(fs) == 0 {
+		f.Formatter.leaveDir(file, corners)
+		return
+	}
 
 	// Sorts the files, based on the flags.
-	if f.args.Sort != ArgSortNone {
+	col, ok := findColumn(f.args.Sort)
+	col = col.bind(f.repos, f.args)
+	if ok && col.less != nil {
 		sort.Slice(fs, func(i, j int) bool {
-			switch f.args.Sort {
-			case ArgSortInode:
-				return fs[i].Inode() < fs[j].Inode()
-			case ArgSortMode:
-				r := strings.NewReplacer("-", "")
-				return r.Replace(fs[i].Mode().String()) < r.Replace(fs[j].Mode().String())
-			case ArgSortSize:
-				return fs[i].Size() < fs[j].Size()
-			case ArgSortAccessed:
-				return fs[i].AccTime().Unix() < fs[j].AccTime().Unix()
-			case ArgSortModified:
-				return fs[i].ModTime().Unix() < fs[j].ModTime().Unix()
-			case ArgSortCreated:
-				return fs[i].CrtTime().Unix() < fs[j].CrtTime().Unix()
-			case ArgSortUser:
-				return fs[i].User().Uid < fs[j].User().Uid
-			case ArgSortGroup:
-				return fs[i].Group().Gid < fs[j].Group().Gid
-			case ArgSortName:
-				return fs[i].Name() < fs[j].Name()
-			default:
-				return true
-			}
+			return col.less(fs[i], fs[j])
+		})
+	} else if ok {
+		sort.Slice(fs, func(i, j int) bool {
+			return col.rawValue(fs[i], f.args) < col.rawValue(fs[j], f.args)
 		})
 	}
 	if f.args.DirsFirst {
@@ -65,6 +70,7 @@ func (f *formatterWrapper) getDir(file ipe.File, grid **gridt.Grid, corners []bo
 	for i, child := range fs {
 		f.getFile(child, *grid, append(corners, i+1 == len(fs)))
 	}
+	f.Formatter.leaveDir(file, corners)
 }
 
 func (f *formatterWrapper) getFile(file ipe.File, grid *gridt.Grid, corners []bool) {
@@ -88,9 +94,25 @@ func (f *formatterWrapper) getFile(file ipe.File, grid *gridt.Grid, corners []bo
 			return
 		}
 	}
+	// Directories are kept when recursing, so the entries under them can
+	// still match the globs.
+	if len(f.args.Glob) > 0 && !(f.args.Recursive && file.IsDir()) && !f.matchAnyGlob(file) {
+		return
+	}
+	for _, g := range f.args.IgnoreGlob {
+		if f.matchGlob(g, file) {
+			return
+		}
+	}
 	if !f.args.All && file.IsDotfile() {
 		return
 	}
+	if f.ignoreFiles != nil && f.ignoreFiles.Match(file.FullName(), file.IsDir()) {
+		return
+	}
+	if !f.args.ShowIgnored && f.repos.gitIgnored(file) {
+		return
+	}
 
 	// Adds the files to the specific formatter.
 	f.Formatter.getFile(file, grid, corners)
@@ -101,21 +123,93 @@ func (f *formatterWrapper) getFile(file ipe.File, grid *gridt.Grid, corners []bo
 	}
 }
 
-func wrap(formatter Formatter, args ArgsInfo) *formatterWrapper {
-	var f formatterWrapper
-	f.Formatter = formatter
-	f.args = args
-	if f.args.Color != ArgColorAuto {
-		color.NoColor = f.args.Color == ArgColorNever
+// matchAnyGlob reports whether the file matches any of the globs to show.
+func (f *formatterWrapper) matchAnyGlob(file ipe.File) bool {
+	for _, g := range f.args.Glob {
+		if f.matchGlob(g, file) {
+			return true
+		}
 	}
+	return false
+}
+
+// matchGlob reports whether the file matches the glob, by its name or by
+// its path relative to the source, based on the arguments. "**" matches
+// any number of directories.
+func (f *formatterWrapper) matchGlob(glob string, file ipe.File) bool {
+	name := file.Name()
+	if f.args.GlobMatch == ArgGlobPath {
+		rel, err := filepath.Rel(f.root, file.FullName())
+		if err != nil {
+			return false
+		}
+		name = filepath.ToSlash(rel)
+	}
+	if f.args.GlobFoldCase {
+		glob, name = strings.ToLower(glob), strings.ToLower(name)
+	}
+	return git.Wildmatch(glob, name)
+}
+
+func wrap(newFormatter func(args ArgsInfo, repos *gitRepos) Formatter, args ArgsInfo) *formatterWrapper {
+	return &formatterWrapper{args: args, newFormatter: newFormatter}
+}
+
+// String outputs the formatter into a correct string.
+func (f formatterWrapper) String() string {
+	var buffer bytes.Buffer
+	f.WriteTo(&buffer)
+	return buffer.String()
+}
+
+// WriteTo reads the sources and writes them into a writer. Each call
+// formats them again with a new formatter, so formatters can be used
+// concurrently and with different writers.
+func (f formatterWrapper) WriteTo(w io.Writer) (int64, error) {
+	f.args = f.args.forWriter(w)
+	f.repos = newGitRepos(f.args)
+	f.Formatter = f.newFormatter(f.args, f.repos)
 	for _, src := range f.args.Sources {
 		file, err := ipe.Read(fixInSrc(src))
 		if err != nil {
-			f.Formatter.appendSource(srcInfo{file, err, nil})
+			f.Formatter.appendSource(srcInfo{file, err, nil, false})
 		} else {
+			f.root = file.FullName()
+			if f.args.IgnoreFiles {
+				f.ignoreFiles = git.NewMatcher(file.FullName(), ignoreFileNames, nil)
+			}
 			g := gridt.New(gridt.LeftToRight, f.args.Separator)
 			f.getDir(file, &g, []bool{})
 		}
 	}
-	return &f
+	return f.Formatter.WriteTo(w)
+}
+
+// forWriter returns the arguments with the automatic options decided for
+// the writer: colors and hidden control characters only when it's a
+// terminal. Colors also respect the `NO_COLOR` convention and dumb
+// terminals.
+func (args ArgsInfo) forWriter(w io.Writer) ArgsInfo {
+	tty := isTerminal(w)
+	if args.Color == ArgColorAuto {
+		if tty && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" {
+			args.Color = ArgColorAlways
+		} else {
+			args.Color = ArgColorNever
+		}
+	}
+	if args.ControlChars == ArgControlAuto {
+		if tty {
+			args.ControlChars = ArgControlHide
+		} else {
+			args.ControlChars = ArgControlShow
+		}
+	}
+	return args
+}
+
+// isTerminal reports whether the writer is a terminal.
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	return ok && terminal.IsTerminal(int(file.Fd()))
 }
@@ -0,0 +1,149 @@
+package ipefmt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIgnoreFiles(t *testing.T) {
+	dir := newTestDir(t, map[string]string{
+		".ipeignore":     "*.log\n/build/\n!keep.log\n",
+		".ignore":        "!a.log\n",
+		"a.log":          "",
+		"keep.log":       "",
+		"main.go":        "",
+		"d.tmp":          "",
+		"build/out.bin":  "",
+		"sub/.ipeignore": "!b.log\n",
+		"sub/.ignore":    "*.tmp\n",
+		"sub/b.log":      "",
+		"sub/c.log":      "",
+		"sub/c.tmp":      "",
+		"sub/build/x":    "",
+	})
+	tests := []struct {
+		name string
+		args ArgsInfo
+		want []string
+	}{
+		{
+			"ignored",
+			ArgsInfo{Recursive: true, IgnoreFiles: true},
+			[]string{"a.log", "d.tmp", "keep.log", "main.go", "sub", "sub/b.log", "sub/build", "sub/build/x"},
+		},
+		{
+			"not recursive",
+			ArgsInfo{IgnoreFiles: true},
+			[]string{"a.log", "d.tmp", "keep.log", "main.go", "sub"},
+		},
+		{
+			"not ignored",
+			ArgsInfo{Recursive: true},
+			[]string{
+				"a.log", "build", "build/out.bin", "d.tmp", "keep.log", "main.go", "sub",
+				"sub/b.log", "sub/build", "sub/build/x", "sub/c.log", "sub/c.tmp",
+			},
+		},
+	}
+	for _, tt := range tests {
+		if got := listPaths(t, dir, tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	dir := newTestDir(t, map[string]string{
+		"main.go":         "",
+		"Main_test.GO":    "",
+		"README.md":       "",
+		"[x].txt":         "",
+		"cmd/ipe/main.go": "",
+		"docs/a.md":       "",
+		"docs/deep/b.md":  "",
+	})
+	tests := []struct {
+		glob  string
+		name  string
+		match string
+		fold  bool
+		want  bool
+	}{
+		{"*.go", "main.go", ArgGlobName, false, true},
+		{"*.go", "Main_test.GO", ArgGlobName, false, false},
+		{"*.go", "Main_test.GO", ArgGlobName, true, true},
+		{"README.MD", "README.md", ArgGlobName, false, false},
+		{"README.MD", "README.md", ArgGlobName, true, true},
+		{"*.go", "cmd/ipe/main.go", ArgGlobName, false, true},
+		{"*.go", "cmd/ipe/main.go", ArgGlobPath, false, false},
+		{"**/*.go", "cmd/ipe/main.go", ArgGlobPath, false, true},
+		{"**/*.go", "main.go", ArgGlobPath, false, true},
+		{"cmd/**", "cmd/ipe/main.go", ArgGlobPath, false, true},
+		{"docs/**/*.md", "docs/a.md", ArgGlobPath, false, true},
+		{"docs/**/*.md", "docs/deep/b.md", ArgGlobPath, false, true},
+		{"docs/*.md", "docs/deep/b.md", ArgGlobPath, false, false},
+		{"?ain.go", "main.go", ArgGlobName, false, true},
+		{"[a-m]*.go", "main.go", ArgGlobName, false, true},
+		{"[!m]*", "main.go", ArgGlobName, false, false},
+		{`\[x\].txt`, "[x].txt", ArgGlobName, false, true},
+		{"[x].txt", "[x].txt", ArgGlobName, false, false},
+	}
+	for _, tt := range tests {
+		f := &formatterWrapper{
+			args: ArgsInfo{GlobMatch: tt.match, GlobFoldCase: tt.fold},
+			root: dir,
+		}
+		if got := f.matchGlob(tt.glob, readTestFile(t, dir, tt.name)); got != tt.want {
+			t.Errorf("matchGlob(%q, %s) with %s matching, folding case %v = %v, want %v",
+				tt.glob, tt.name, tt.match, tt.fold, got, tt.want)
+		}
+	}
+}
+
+func TestGlob(t *testing.T) {
+	dir := newTestDir(t, map[string]string{
+		"main.go":         "",
+		"Main_test.GO":    "",
+		"README.md":       "",
+		"[x].txt":         "",
+		"cmd/ipe/main.go": "",
+		"docs/a.md":       "",
+		"docs/deep/b.md":  "",
+	})
+	tests := []struct {
+		name string
+		args ArgsInfo
+		want []string
+	}{
+		{
+			"glob",
+			ArgsInfo{Glob: []string{"*.go"}},
+			[]string{"main.go"},
+		},
+		{
+			"glob folding case",
+			ArgsInfo{Glob: []string{"*.go"}, GlobFoldCase: true},
+			[]string{"Main_test.GO", "main.go"},
+		},
+		{
+			"glob recursing",
+			ArgsInfo{Glob: []string{"*.md"}, Recursive: true},
+			[]string{"README.md", "cmd", "cmd/ipe", "docs", "docs/a.md", "docs/deep", "docs/deep/b.md"},
+		},
+		{
+			"ignore glob",
+			ArgsInfo{IgnoreGlob: []string{"docs"}, Recursive: true},
+			[]string{"Main_test.GO", "README.md", "[x].txt", "cmd", "cmd/ipe", "cmd/ipe/main.go", "main.go"},
+		},
+		{
+			"ignore glob by path",
+			ArgsInfo{IgnoreGlob: []string{"**/*.go"}, GlobMatch: ArgGlobPath, Recursive: true},
+			[]string{"Main_test.GO", "README.md", "[x].txt", "cmd", "cmd/ipe", "docs", "docs/a.md", "docs/deep", "docs/deep/b.md"},
+		},
+	}
+	for _, tt := range tests {
+		if got := listPaths(t, dir, tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package ipefmt
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Nhanderu/ipe"
+)
+
+func init() {
+	registerFormat(ArgFormatXML, func(args ArgsInfo, repos *gitRepos) VisitorFormatter {
+		return newXMLFormatter(args, repos)
+	})
+}
+
+// xmlFormatter writes the entries as XML elements, compatible with the
+// output of `tree -X`.
+//
+// The element of an entry is only closed when the next event arrives,
+// because only then it's known if it has children. The Git status and the
+// last commit, if known, are extra attributes.
+type xmlFormatter struct {
+	args    ArgsInfo
+	repos   *gitRepos
+	buffer  bytes.Buffer
+	stack   []string
+	pending string
+	dirs    int
+	files   int
+}
+
+func newXMLFormatter(args ArgsInfo, repos *gitRepos) *xmlFormatter {
+	return &xmlFormatter{args: args, repos: repos}
+}
+
+func (f *xmlFormatter) EnterDir(dir ipe.File, corners []bool) {
+	if len(corners) == 0 {
+		f.closePending()
+		f.open(dir, dir.FullName())
+	}
+	f.buffer.WriteString("\n")
+	f.stack = append(f.stack, f.pending)
+	f.pending = ""
+}
+
+func (f *xmlFormatter) VisitFile(file ipe.File, corners []bool) {
+	f.closePending()
+	f.open(file, file.Name())
+	if file.IsDir() {
+		f.dirs++
+	} else {
+		f.files++
+	}
+}
+
+func (f *xmlFormatter) LeaveDir(dir ipe.File, corners []bool) {
+	f.closePending()
+	elem := f.stack[len(f.stack)-1]
+	f.stack = f.stack[:len(f.stack)-1]
+	f.indent()
+	fmt.Fprintf(&f.buffer, "</%s>\n", elem)
+}
+
+func (f *xmlFormatter) Error(err error) {
+	f.closePending()
+	f.indent()
+	f.buffer.WriteString("<error>")
+	xml.EscapeText(&f.buffer, []byte(err.Error()))
+	f.buffer.WriteString("</error>\n")
+}
+
+func (f xmlFormatter) WriteTo(w io.Writer) (int64, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(xml.Header)
+	buffer.WriteString("<tree>\n")
+	buffer.Write(f.buffer.Bytes())
+	if f.pending != "" {
+		fmt.Fprintf(&buffer, "</%s>\n", f.pending)
+	}
+	fmt.Fprintf(&buffer, "  <report>\n    <directories>%d</directories>\n    <files>%d</files>\n  </report>\n", f.dirs, f.files)
+	buffer.WriteString("</tree>\n")
+	return buffer.WriteTo(w)
+}
+
+// open writes the opening tag of the entry, leaving it pending.
+func (f *xmlFormatter) open(file ipe.File, name string) {
+	f.pending = xmlElement(file)
+	f.indent()
+	fmt.Fprintf(&f.buffer, "<%s", f.pending)
+	f.attr("name", name)
+	if file.IsSymlink() {
+		target, _ := os.Readlink(file.FullName())
+		f.attr("target", target)
+	}
+	f.attr("mode", fmt.Sprintf("%04o", file.Mode().Perm()))
+	f.attr("prot", file.Mode().String())
+	if u := file.User(); u != nil {
+		f.attr("user", u.Username)
+	}
+	if g := file.Group(); g != nil {
+		f.attr("group", g.Name)
+	}
+	f.attr("size", strconv.FormatInt(file.Size(), 10))
+	f.attr("time", xmlTime(file.ModTime()))
+	f.attr("atime", xmlTime(file.AccTime()))
+	f.attr("ctime", xmlTime(file.CrtTime()))
+	if st, ok := f.repos.gitStatus(file); ok {
+		f.attr("git", st.String())
+	}
+	if c, ok := f.repos.lastCommit(file); ok {
+		f.attr("commit", c.Hash.String())
+		f.attr("author", c.Author)
+		f.attr("email", c.Email)
+		f.attr("committed", xmlTime(c.Time))
+	}
+	f.buffer.WriteString(">")
+}
+
+func (f *xmlFormatter) closePending() {
+	if f.pending != "" {
+		fmt.Fprintf(&f.buffer, "</%s>\n", f.pending)
+		f.pending = ""
+	}
+}
+
+func (f *xmlFormatter) indent() {
+	f.buffer.WriteString(strings.Repeat("  ", len(f.stack)+1))
+}
+
+func (f *xmlFormatter) attr(name, value string) {
+	fmt.Fprintf(&f.buffer, ` %s="`, name)
+	xml.EscapeText(&f.buffer, []byte(value))
+	f.buffer.WriteString(`"`)
+}
+
+// xmlTime returns the timestamp as seconds since the Unix epoch, like
+// `tree -X` shows it.
+func xmlTime(t time.Time) string {
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+// xmlElement returns the name of the element of the file, as `tree -X`
+// names them.
+func xmlElement(file ipe.File) string {
+	if file.IsDevice() {
+		if file.Mode()&os.ModeCharDevice != 0 {
+			return "char"
+		}
+		return "block"
+	}
+	return fileType(file)
+}